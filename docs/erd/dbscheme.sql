@@ -7,14 +7,29 @@ CREATE TABLE settings
   PRIMARY KEY (id)
 );
 
+CREATE UNIQUE INDEX settings_name_user_id_key
+  ON settings (name, (COALESCE(user_id, 0)));
+
+CREATE TABLE telegram_sessions
+(
+  name         TEXT      NOT NULL,
+  data         BYTEA     NOT NULL,
+  time_updated TIMESTAMP NOT NULL,
+  PRIMARY KEY (name)
+);
+
 CREATE TABLE torrent_x_user
 (
-  torrent_id BIGINT  NOT NULL,
-  user_id    BIGINT  NOT NULL,
-  sent       BOOLEAN NOT NULL DEFAULT FALSE,
+  torrent_id BIGINT    NOT NULL,
+  user_id    BIGINT    NOT NULL,
+  sent       BOOLEAN   NOT NULL DEFAULT FALSE,
+  time_added TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
   PRIMARY KEY (torrent_id, user_id)
 );
 
+CREATE INDEX torrent_x_user_user_id_time_added_idx
+  ON torrent_x_user (user_id, time_added);
+
 CREATE TABLE torrents
 (
   id            BIGINT    NOT NULL GENERATED ALWAYS AS IDENTITY,
@@ -26,6 +41,7 @@ CREATE TABLE torrents
   time_started  TIMESTAMP DEFAULT NULL,
   time_finished TIMESTAMP DEFAULT NULL,
   error         TEXT      DEFAULT NULL,
+  topic_id      BIGINT    DEFAULT NULL,
   PRIMARY KEY (id)
 );
 
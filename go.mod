@@ -3,6 +3,7 @@ module github.com/aleksander-git/telegram-torrent
 go 1.22.5
 
 require (
+	github.com/anacrolix/generics v0.0.2
 	github.com/anacrolix/torrent v1.56.1
 	github.com/go-bittorrent/magneturi v0.1.0
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
@@ -12,6 +13,7 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/pressly/goose/v3 v3.21.1
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/time v0.5.0
 )
 
 require (
@@ -21,7 +23,6 @@ require (
 	github.com/anacrolix/chansync v0.5.1 // indirect
 	github.com/anacrolix/dht/v2 v2.21.1 // indirect
 	github.com/anacrolix/envpprof v1.3.0 // indirect
-	github.com/anacrolix/generics v0.0.2 // indirect
 	github.com/anacrolix/go-libutp v1.3.1 // indirect
 	github.com/anacrolix/log v0.15.2 // indirect
 	github.com/anacrolix/missinggo v1.3.0 // indirect
@@ -102,7 +103,6 @@ require (
 	golang.org/x/net v0.27.0 // indirect
 	golang.org/x/sync v0.7.0 // indirect
 	golang.org/x/sys v0.22.0 // indirect
-	golang.org/x/time v0.5.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	lukechampine.com/blake3 v1.3.0 // indirect
 	modernc.org/libc v1.55.6 // indirect
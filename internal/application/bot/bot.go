@@ -1,14 +1,26 @@
 package bot
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"log/slog"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/aleksander-git/telegram-torrent/internal/bot"
 	"github.com/aleksander-git/telegram-torrent/internal/database/backend"
+	"github.com/aleksander-git/telegram-torrent/internal/gotdclient"
+	"github.com/aleksander-git/telegram-torrent/internal/settings"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/tg"
 )
 
+// userLoginTimeout limits time the admin has to send the login code
+const userLoginTimeout = 10 * time.Minute
+
 func Run() {
 	logger := slog.New(slog.NewTextHandler(log.Writer(), nil))
 
@@ -19,11 +31,127 @@ func Run() {
 		return
 	}
 
-	tgbot, err := bot.New(os.Getenv("BOT_TOKEN"), logger, db)
+	tgbot, err := newBot(logger, db)
 	if err != nil {
 		logger.Error("unable to create bot", "error", err)
 		return
 	}
 
+	// Настройки перечитываются при изменении в базе данных любым экземпляром
+	botSettings := settings.New(db)
+	tgbot.WithSettings(botSettings)
+
+	listener := settings.NewListener(logger, os.Getenv("DATABASE_CONNECTION_STRING"), botSettings)
+	defer listener.Close()
+
+	go func() {
+		if err := listener.Run(context.Background()); err != nil {
+			logger.Error("unable to listen for settings changes", "error", err)
+		}
+	}()
+
+	if adminID := os.Getenv("ADMIN_ID"); adminID != "" {
+		id, err := strconv.ParseInt(adminID, 10, 64)
+		if err != nil {
+			logger.Error("unable to parse ADMIN_ID", "error", err)
+			return
+		}
+		tgbot.WithAdmin(id)
+	}
+
+	// Вход в пользовательский аккаунт, код запрашивается у администратора через бота
+	if phone := os.Getenv("USER_PHONE"); phone != "" {
+		go func() {
+			if err := loginUser(logger, db, tgbot, phone); err != nil {
+				logger.Error("unable to login user account", "error", err)
+			}
+		}()
+	}
+
 	tgbot.Start()
 }
+
+// newBot creates bot receiving updates through Bot API
+// or through gotd client if BOT_BACKEND is "gotd"
+func newBot(logger *slog.Logger, db *backend.Database) (*bot.Bot, error) {
+	if os.Getenv("BOT_BACKEND") != "gotd" {
+		return bot.New(os.Getenv("BOT_TOKEN"), logger, db)
+	}
+
+	appID, err := strconv.Atoi(os.Getenv("APP_ID"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse APP_ID: %w", err)
+	}
+
+	storage, err := sessionStorage(db, "bot")
+	if err != nil {
+		return nil, fmt.Errorf("sessionStorage(): %w", err)
+	}
+
+	dispatcher := tg.NewUpdateDispatcher()
+	client := gotdclient.New(appID, os.Getenv("APP_HASH"),
+		gotdclient.WithLogger(logger),
+		gotdclient.WithSessionStorage(storage),
+		gotdclient.WithUpdateHandler(dispatcher),
+	)
+
+	api := client.API()
+	resolver := gotdclient.NewResolver(api, message.NewSender(api))
+	messenger := bot.NewGotdMessenger(client, dispatcher, resolver)
+
+	if err := client.Connect(context.Background(), os.Getenv("BOT_TOKEN")); err != nil {
+		return nil, fmt.Errorf("client.Connect(): %w", err)
+	}
+
+	return bot.NewWithMessenger(messenger, logger, db), nil
+}
+
+// loginUser logs in the user account and stores its session in the database
+func loginUser(logger *slog.Logger, db *backend.Database, tgbot *bot.Bot, phone string) error {
+	appID, err := strconv.Atoi(os.Getenv("APP_ID"))
+	if err != nil {
+		return fmt.Errorf("unable to parse APP_ID: %w", err)
+	}
+
+	storage, err := sessionStorage(db, "user")
+	if err != nil {
+		return fmt.Errorf("sessionStorage(): %w", err)
+	}
+
+	client := gotdclient.New(appID, os.Getenv("APP_HASH"), gotdclient.WithSessionStorage(storage))
+
+	ctx, cancel := context.WithTimeout(context.Background(), userLoginTimeout)
+	defer cancel()
+
+	err = client.ConnectUser(ctx, gotdclient.UserCredentials{
+		Phone:    phone,
+		Password: os.Getenv("USER_PASSWORD"),
+		Codes:    gotdclient.CodeProviderFunc(tgbot.LoginCode),
+	})
+	if err != nil {
+		return fmt.Errorf("client.ConnectUser(): %w", err)
+	}
+	defer client.Close()
+
+	logger.Info("user account session is saved")
+
+	return nil
+}
+
+// sessionStorage stores the session named name in the database,
+// the session is encrypted if SESSION_SECRET is set
+func sessionStorage(db *backend.Database, name string) (telegram.SessionStorage, error) {
+	var storage telegram.SessionStorage = gotdclient.NewDatabaseStorage(db, name)
+
+	secret := os.Getenv("SESSION_SECRET")
+	if secret == "" {
+		return storage, nil
+	}
+
+	storage, err := gotdclient.NewEncryptedStorage(storage, []byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("gotdclient.NewEncryptedStorage(): %w", err)
+	}
+
+	return storage, nil
+}
@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+	"github.com/aleksander-git/telegram-torrent/internal/settings"
+)
+
+const (
+	loginCodeRequestTemplate = `Telegram отправил код входа для аккаунта %s.
+
+Отправьте его ответным сообщением, разделив цифры пробелами, например: 1 2 3 4 5`
+
+	loginCodeReceivedAnswer = "Код получен, выполняется вход"
+
+	bandwidthCommand = "/bandwidth"
+
+	bandwidthAnswerTemplate = `Скорость загрузки: %s, отдачи: %s
+
+` + bandwidthUsageAnswer
+
+	bandwidthUsageAnswer = "Изменить: /bandwidth <загрузка> <отдача> в КБ/с, 0 - без ограничений"
+
+	bandwidthChangedAnswer = "Ограничения скорости изменены"
+
+	unlimitedRate = "без ограничений"
+)
+
+// maxBandwidthRate is a maximal rate in kilobytes per second set by the bandwidth command,
+// so it doesn't overflow when converted to bytes per second
+const maxBandwidthRate = 1 << 30
+
+// errNoAdmin is returned if admin is not set by WithAdmin
+var errNoAdmin = errors.New("admin is not set")
+
+// WithAdmin sets ID of the admin user, whose chat with the bot is used for service requests like login codes
+func (b *Bot) WithAdmin(adminID int64) *Bot {
+	b.adminID = adminID
+
+	return b
+}
+
+// LoginCode asks admin for the login code sent by Telegram to the phone
+// and waits for the admin's answer.
+//
+// It can be used as gotdclient.CodeProvider with gotdclient.CodeProviderFunc
+func (b *Bot) LoginCode(ctx context.Context, phone string) (string, error) {
+	if b.adminID == 0 {
+		return "", errNoAdmin
+	}
+
+	codes := make(chan string, 1)
+
+	b.mu.Lock()
+	b.loginCodes = codes
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		if b.loginCodes == codes {
+			b.loginCodes = nil
+		}
+		b.mu.Unlock()
+	}()
+
+	// the admin chat with the bot is a private chat, its ID is the admin ID
+	if err := b.send(b.adminID, fmt.Sprintf(loginCodeRequestTemplate, phone)); err != nil {
+		return "", fmt.Errorf("cannot send login code request: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return "", fmt.Errorf("login code is not received: %w", ctx.Err())
+	case code := <-codes:
+		return code, nil
+	}
+}
+
+// handleLoginCode passes the admin's message to LoginCode waiting for it.
+// It returns false if the message isn't a login code
+func (b *Bot) handleLoginCode(userID int64, chatID int64, text string) (bool, error) {
+	if b.adminID == 0 || userID != b.adminID {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	codes := b.loginCodes
+	b.loginCodes = nil
+	b.mu.Unlock()
+
+	if codes == nil {
+		return false, nil
+	}
+
+	codes <- text
+
+	if err := b.send(chatID, loginCodeReceivedAnswer); err != nil {
+		return true, fmt.Errorf("cannot send login code received answer: %w", err)
+	}
+
+	return true, nil
+}
+
+// WithBandwidth sets limits of the torrent client changed by the bandwidth command,
+// if the bot runs in the same process. Without it the limits are changed only in the settings
+func (b *Bot) WithBandwidth(bandwidth *loader.Bandwidth) *Bot {
+	b.bandwidth = bandwidth
+
+	return b
+}
+
+// handleBandwidthCommand shows or changes the global rate limits of the torrent client.
+// It returns false if the message isn't the admin's bandwidth command
+func (b *Bot) handleBandwidthCommand(userID int64, chatID int64, text string) (bool, error) {
+	fields := strings.Fields(text)
+	if b.adminID == 0 || userID != b.adminID || len(fields) == 0 || fields[0] != bandwidthCommand {
+		return false, nil
+	}
+
+	ctx := context.Background()
+
+	var answer string
+	switch len(fields) {
+	case 1:
+		download, err := b.settings.Int(ctx, settings.DownloadRate, 0)
+		if err != nil {
+			return true, fmt.Errorf("b.settings.Int(%q): %w", settings.DownloadRate.Name, err)
+		}
+
+		upload, err := b.settings.Int(ctx, settings.UploadRate, 0)
+		if err != nil {
+			return true, fmt.Errorf("b.settings.Int(%q): %w", settings.UploadRate.Name, err)
+		}
+
+		answer = fmt.Sprintf(bandwidthAnswerTemplate, formatRate(download), formatRate(upload))
+
+	case 3:
+		download, downloadErr := strconv.ParseInt(fields[1], 10, 64)
+		upload, uploadErr := strconv.ParseInt(fields[2], 10, 64)
+		if downloadErr != nil || uploadErr != nil ||
+			download < 0 || upload < 0 || download > maxBandwidthRate || upload > maxBandwidthRate {
+			answer = bandwidthUsageAnswer
+			break
+		}
+
+		// processes running the torrent client apply the settings
+		// when they are notified about the change, see loader.Bandwidth.Reload
+		if err := b.settings.SetInt(ctx, settings.DownloadRate, 0, download<<10); err != nil {
+			return true, fmt.Errorf("b.settings.SetInt(%q): %w", settings.DownloadRate.Name, err)
+		}
+
+		if err := b.settings.SetInt(ctx, settings.UploadRate, 0, upload<<10); err != nil {
+			return true, fmt.Errorf("b.settings.SetInt(%q): %w", settings.UploadRate.Name, err)
+		}
+
+		if b.bandwidth != nil {
+			b.bandwidth.SetLimits(download<<10, upload<<10)
+		}
+
+		answer = bandwidthChangedAnswer
+
+	default:
+		answer = bandwidthUsageAnswer
+	}
+
+	if err := b.send(chatID, answer); err != nil {
+		return true, fmt.Errorf("cannot send bandwidth answer: %w", err)
+	}
+
+	return true, nil
+}
+
+// formatRate formats rate in bytes per second as kilobytes per second
+func formatRate(bytesPerSecond int64) string {
+	if bytesPerSecond == 0 {
+		return unlimitedRate
+	}
+
+	return fmt.Sprintf("%d КБ/с", bytesPerSecond>>10)
+}
@@ -4,62 +4,101 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/aleksander-git/telegram-torrent/internal/database/backend"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+	"github.com/aleksander-git/telegram-torrent/internal/quota"
+	"github.com/aleksander-git/telegram-torrent/internal/settings"
 )
 
 type Bot struct {
-	botAPI           *tgbotapi.BotAPI
+	messenger        Messenger
 	logger           *slog.Logger
 	usersLastCommand map[string]string
 	db               DBInterface
+	settings         *settings.Settings
+	quota            *quota.Checker
+	bandwidth        *loader.Bandwidth
+
+	adminID int64
+
+	mu sync.Mutex
+	// loginCodes receives the next admin's message while LoginCode waits for it
+	loginCodes chan string
 }
 
 type DBInterface interface {
 	AddUser(ctx context.Context, arg backend.AddUserParams) error
 	GetUser(ctx context.Context, id int64) (backend.User, error)
-	AddTorrent(ctx context.Context, arg backend.AddTorrentParams) error
+	AddUserTorrent(ctx context.Context, userID int64, chatID int64, torrentLink string, timeAdded time.Time) error
 	GetTorrent(ctx context.Context, torrentLink string) (backend.Torrent, error)
 	GetTorrents(ctx context.Context, userID int64) ([]backend.Torrent, error)
-	GetSetting(ctx context.Context, userID int64, key string) (string, error)
+	settings.Store
+	quota.Store
 }
 
 func New(token string, logger *slog.Logger, db DBInterface) (*Bot, error) {
-	bot, err := tgbotapi.NewBotAPI(token)
+	messenger, err := NewBotAPIMessenger(token)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get bot API: %w", err)
+		return nil, fmt.Errorf("NewBotAPIMessenger(): %w", err)
 	}
 
+	return NewWithMessenger(messenger, logger, db), nil
+}
+
+// NewWithMessenger creates bot receiving and sending messages through the messenger,
+// e.g. GotdMessenger sharing the connection with uploads
+func NewWithMessenger(messenger Messenger, logger *slog.Logger, db DBInterface) *Bot {
+	botSettings := settings.New(db)
+
 	return &Bot{
-		botAPI:           bot,
+		messenger:        messenger,
 		logger:           logger,
 		usersLastCommand: make(map[string]string),
 		db:               db,
-	}, nil
+		settings:         botSettings,
+		quota:            quota.New(botSettings, db),
+	}
 }
 
-func (b *Bot) Start() {
-	b.botAPI.Debug = true
+// WithSettings replaces settings created from the database,
+// so the bot shares their cache with other components
+func (b *Bot) WithSettings(s *settings.Settings) *Bot {
+	b.settings = s
+	b.quota = quota.New(s, b.db)
 
-	b.logger.Info(fmt.Sprintf("started on account %s", b.botAPI.Self.UserName))
+	return b
+}
 
-	offset := 0
-	u := tgbotapi.NewUpdate(offset)
+func (b *Bot) Start() {
+	ctx := context.Background()
 
-	timeoutSeconds := 60
-	u.Timeout = timeoutSeconds
+	userName, err := b.messenger.UserName(ctx)
+	if err != nil {
+		b.logger.Error(fmt.Sprintf("cannot get bot username: %s", err))
+	}
 
-	updates := b.botAPI.GetUpdatesChan(u)
+	b.logger.Info(fmt.Sprintf("started on account %s", userName))
 
-	for update := range updates {
-		if update.Message != nil {
-			b.logger.Info("received message %q from %q", update.Message.Text, update.Message.From.UserName)
+	err = b.messenger.Listen(ctx, func(ctx context.Context, msg Message) {
+		b.logger.Info(fmt.Sprintf("received message %q from %q", msg.Text, msg.UserName))
 
-			err := b.handleMessage(update.Message)
-			if err != nil {
-				b.logger.Error(fmt.Sprintf("cannot handle message: %s", err))
-			}
+		err := b.handleMessage(msg)
+		if err != nil {
+			b.logger.Error(fmt.Sprintf("cannot handle message: %s", err))
 		}
+	})
+	if err != nil {
+		b.logger.Error(fmt.Sprintf("cannot receive messages: %s", err))
 	}
 }
+
+// send sends the text to the chat
+func (b *Bot) send(chatID int64, text string) error {
+	return b.messenger.Send(context.Background(), OutgoingMessage{
+		ChatID: chatID,
+		Text:   text,
+	})
+}
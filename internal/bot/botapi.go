@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// BotAPIMessenger is a Messenger using Telegram Bot API
+type BotAPIMessenger struct {
+	botAPI *tgbotapi.BotAPI
+}
+
+var _ Messenger = (*BotAPIMessenger)(nil)
+
+func NewBotAPIMessenger(token string) (*BotAPIMessenger, error) {
+	botAPI, err := tgbotapi.NewBotAPI(token)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get bot API: %w", err)
+	}
+
+	return &BotAPIMessenger{
+		botAPI: botAPI,
+	}, nil
+}
+
+func (m *BotAPIMessenger) UserName(_ context.Context) (string, error) {
+	return m.botAPI.Self.UserName, nil
+}
+
+func (m *BotAPIMessenger) Listen(ctx context.Context, handle func(ctx context.Context, msg Message)) error {
+	m.botAPI.Debug = true
+
+	offset := 0
+	u := tgbotapi.NewUpdate(offset)
+
+	timeoutSeconds := 60
+	u.Timeout = timeoutSeconds
+
+	updates := m.botAPI.GetUpdatesChan(u)
+	defer m.botAPI.StopReceivingUpdates()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+
+			if update.Message == nil || update.Message.From == nil {
+				continue
+			}
+
+			handle(ctx, Message{
+				ChatID:   update.Message.Chat.ID,
+				UserID:   update.Message.From.ID,
+				UserName: update.Message.From.UserName,
+				Text:     update.Message.Text,
+			})
+		}
+	}
+}
+
+func (m *BotAPIMessenger) Send(_ context.Context, msg OutgoingMessage) error {
+	message := tgbotapi.NewMessage(msg.ChatID, msg.Text)
+	message.ParseMode = string(msg.ParseMode)
+
+	if _, err := m.botAPI.Send(message); err != nil {
+		return fmt.Errorf("m.botAPI.Send(): %w", err)
+	}
+
+	return nil
+}
+
+func (m *BotAPIMessenger) IsMember(_ context.Context, chatID int64, userID int64) (bool, error) {
+	config := tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: chatID,
+			UserID: userID,
+		}}
+	chatMember, err := m.botAPI.GetChatMember(config)
+	if err != nil {
+		return false, fmt.Errorf("m.botAPI.GetChatMember(%#v): %w", config, err)
+	}
+
+	return chatMember.Status != "left" && chatMember.Status != "kicked", nil
+}
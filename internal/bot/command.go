@@ -3,12 +3,11 @@ package bot
 import (
 	"context"
 	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/aleksander-git/telegram-torrent/internal/database/backend"
+	"github.com/aleksander-git/telegram-torrent/internal/settings"
 	"github.com/go-bittorrent/magneturi"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 const (
@@ -33,6 +32,10 @@ const (
 	unavailableAnswer = "Сервер в данный момент не доступен. Повторите запрос позже"
 
 	notSubscribeAnswerTemplate = "Для доступа к функциям необходимо подписаться на канал %s. Подпишитесь и повторите запрос снова"
+
+	quotaExceededAnswerTemplate = "Вы достигли лимита: %d торрентов за 24 часа. Следующий торрент можно будет добавить после %s"
+
+	quotaResetTimeLayout = "02.01.2006 15:04 MST"
 )
 
 func validateTorrentLink(link string) error {
@@ -45,34 +48,23 @@ func validateTorrentLink(link string) error {
 
 func (b *Bot) isUserSubscribed(userID int64) (bool, error) {
 	ctx := context.Background()
-	channelID, err := b.db.GetSetting(ctx, userID, "channel_id")
-	if err != nil {
-		return false, fmt.Errorf("b.db.GetSetting(%q): %w", "channel_id", err)
-	}
-
-	id, err := strconv.ParseInt(channelID, 10, 64)
+	id, err := b.settings.Int(ctx, settings.ChannelID, userID)
 	if err != nil {
-		return false, fmt.Errorf("cannot parse channel_id to int64: %w", err)
+		return false, fmt.Errorf("b.settings.Int(%q): %w", settings.ChannelID.Name, err)
 	}
 
-	config := tgbotapi.GetChatMemberConfig{
-		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
-			ChatID: id,
-			UserID: userID,
-		}}
-	chatMember, err := b.botAPI.GetChatMember(config)
+	member, err := b.messenger.IsMember(ctx, id, userID)
 	if err != nil {
-		return false, fmt.Errorf("b.botAPI.GetChatMember(%#v): %w", config, err)
+		return false, fmt.Errorf("b.messenger.IsMember(ctx, %d, %d): %w", id, userID, err)
 	}
 
-	return chatMember.Status != "left" && chatMember.Status != "kicked", nil
+	return member, nil
 }
 
 func (b *Bot) handleStartCommand(userName string, chatID int64) error {
 	delete(b.usersLastCommand, userName)
 
-	msg := tgbotapi.NewMessage(chatID, "Добро пожаловать! "+helpAnswer)
-	_, err := b.botAPI.Send(msg)
+	err := b.send(chatID, "Добро пожаловать! "+helpAnswer)
 	if err != nil {
 		return fmt.Errorf("cannot send start answer: %w", err)
 	}
@@ -83,8 +75,7 @@ func (b *Bot) handleStartCommand(userName string, chatID int64) error {
 func (b *Bot) handleHelpCommand(userName string, chatID int64) error {
 	delete(b.usersLastCommand, userName)
 
-	msg := tgbotapi.NewMessage(chatID, helpAnswer)
-	_, err := b.botAPI.Send(msg)
+	err := b.send(chatID, helpAnswer)
 	if err != nil {
 		return fmt.Errorf("cannot send help answer: %w", err)
 	}
@@ -92,9 +83,39 @@ func (b *Bot) handleHelpCommand(userName string, chatID int64) error {
 	return nil
 }
 
-func (b *Bot) handleNewTorrentCommand(userName string, chatID int64) error {
-	msg := tgbotapi.NewMessage(chatID, newTorrentAnswer)
-	if _, err := b.botAPI.Send(msg); err != nil {
+// checkTorrentsQuota answers the user and returns false if the user can't add torrents now
+func (b *Bot) checkTorrentsQuota(userID int64, chatID int64) (bool, error) {
+	usage, err := b.quota.Torrents(context.Background(), userID)
+	if err != nil {
+		b.logger.Error(fmt.Sprintf("b.quota.Torrents(%d): %s", userID, err))
+		if err := b.send(chatID, unavailableAnswer); err != nil {
+			return false, fmt.Errorf("cannot send bot unavailable message: %w", err)
+		}
+
+		return false, nil
+	}
+
+	if !usage.Exceeded() {
+		return true, nil
+	}
+
+	resetAt := usage.ResetAt.UTC().Format(quotaResetTimeLayout)
+	if err := b.send(chatID, fmt.Sprintf(quotaExceededAnswerTemplate, usage.Limit, resetAt)); err != nil {
+		return false, fmt.Errorf("cannot send quota exceeded answer: %w", err)
+	}
+
+	return false, nil
+}
+
+func (b *Bot) handleNewTorrentCommand(userName string, chatID int64, userID int64) error {
+	delete(b.usersLastCommand, userName)
+
+	allowed, err := b.checkTorrentsQuota(userID, chatID)
+	if err != nil || !allowed {
+		return err
+	}
+
+	if err := b.send(chatID, newTorrentAnswer); err != nil {
 		return fmt.Errorf("cannot send new torrent answer: %w", err)
 	}
 
@@ -105,7 +126,7 @@ func (b *Bot) handleNewTorrentCommand(userName string, chatID int64) error {
 func (b *Bot) handleListTorrentCommand(userName string, chatID int64, userID int64) error {
 	delete(b.usersLastCommand, userName)
 
-	msg := tgbotapi.NewMessage(chatID, "")
+	msg := OutgoingMessage{ChatID: chatID}
 
 	ctx := context.Background()
 	torrents, err := b.db.GetTorrents(ctx, userID)
@@ -114,10 +135,10 @@ func (b *Bot) handleListTorrentCommand(userName string, chatID int64, userID int
 		msg.Text = unavailableAnswer
 	} else {
 		msg.Text = torrentsToString(torrents)
-		msg.ParseMode = "Markdown"
+		msg.ParseMode = ParseModeMarkdown
 	}
 
-	_, err = b.botAPI.Send(msg)
+	err = b.messenger.Send(ctx, msg)
 	if err != nil {
 		return fmt.Errorf("cannot send list torrent answer: %w", err)
 	}
@@ -125,29 +146,32 @@ func (b *Bot) handleListTorrentCommand(userName string, chatID int64, userID int
 	return nil
 }
 
-func (b *Bot) handleAddingNewTorrent(userName string, chatID int64, link string) error {
-	msg := tgbotapi.NewMessage(chatID, "")
+func (b *Bot) handleAddingNewTorrent(userName string, chatID int64, userID int64, link string) error {
+	var text string
 
 	if err := validateTorrentLink(link); err != nil {
 		b.logger.Error(fmt.Sprintf("b.handleAddingNewTorrent(%q, %d, %q): %s", userName, chatID, link, err))
-		msg.Text = "Неверная Magnet-ссылка. Проверьте и отправьте снова"
+		text = "Неверная Magnet-ссылка. Проверьте и отправьте снова"
 	} else {
-		ctx := context.Background()
-		addTorrentParams := backend.AddTorrentParams{
-			TorrentLink: link,
-			TimeAdded:   time.Now(),
+		// the quota could be spent by other messages since the command
+		allowed, err := b.checkTorrentsQuota(userID, chatID)
+		if err != nil || !allowed {
+			delete(b.usersLastCommand, userName)
+			return err
 		}
-		if err := b.db.AddTorrent(ctx, addTorrentParams); err != nil {
+
+		ctx := context.Background()
+		if err := b.db.AddUserTorrent(ctx, userID, chatID, link, time.Now().UTC()); err != nil {
 			wrappedErr := fmt.Errorf("adding torrent failed for user %q in chat %d, link %q: %w", userName, chatID, link, err)
 			b.logger.Error(wrappedErr.Error())
-			msg.Text = unavailableAnswer
+			text = unavailableAnswer
 		} else {
 			delete(b.usersLastCommand, userName)
-			msg.Text = addedTorrentAnswer
+			text = addedTorrentAnswer
 		}
 	}
 
-	_, err := b.botAPI.Send(msg)
+	err := b.send(chatID, text)
 	if err != nil {
 		return fmt.Errorf("cannot send answer after adding torrent: %w", err)
 	}
@@ -155,34 +179,49 @@ func (b *Bot) handleAddingNewTorrent(userName string, chatID int64, link string)
 	return nil
 }
 
-func (b *Bot) handleMessage(receivedMessage *tgbotapi.Message) error {
-	userName := receivedMessage.From.UserName
-	chatID := receivedMessage.Chat.ID
-	userID := receivedMessage.From.ID
+func (b *Bot) handleMessage(receivedMessage Message) error {
+	userName := receivedMessage.UserName
+	chatID := receivedMessage.ChatID
+	userID := receivedMessage.UserID
+
+	if handled, err := b.handleLoginCode(userID, chatID, receivedMessage.Text); handled {
+		if err != nil {
+			return fmt.Errorf("b.handleLoginCode(%d, %d): %w", userID, chatID, err)
+		}
+
+		return nil
+	}
+
+	if handled, err := b.handleBandwidthCommand(userID, chatID, receivedMessage.Text); handled {
+		if err != nil {
+			return fmt.Errorf("b.handleBandwidthCommand(%d, %d): %w", userID, chatID, err)
+		}
+
+		return nil
+	}
 
 	subscribed, err := b.isUserSubscribed(userID)
 	if err != nil {
 		b.logger.Error(fmt.Sprintf("b.isUserSubscribed(%d): %s", userID, err))
-		msg := tgbotapi.NewMessage(chatID, unavailableAnswer)
-		_, err := b.botAPI.Send(msg)
+		err := b.send(chatID, unavailableAnswer)
 		if err != nil {
 			return fmt.Errorf("cannot send bot unavailable message: %w", err)
 		}
 	}
 
 	if !subscribed {
-		msg := tgbotapi.NewMessage(chatID, "")
+		var text string
 
 		ctx := context.Background()
-		chatLink, err := b.db.GetSetting(ctx, userID, "channel_link")
+		chatLink, err := b.settings.String(ctx, settings.ChannelLink, userID)
 		if err != nil {
-			b.logger.Error(fmt.Sprintf("b.db.GetSetting(%d, %q): %s", userID, "channel_link", err))
-			msg.Text = unavailableAnswer
+			b.logger.Error(fmt.Sprintf("b.settings.String(%d, %q): %s", userID, settings.ChannelLink.Name, err))
+			text = unavailableAnswer
 		} else {
-			msg.Text = fmt.Sprintf(notSubscribeAnswerTemplate, chatLink)
+			text = fmt.Sprintf(notSubscribeAnswerTemplate, chatLink)
 		}
 
-		_, err = b.botAPI.Send(msg)
+		err = b.send(chatID, text)
 		if err != nil {
 			return fmt.Errorf("cannot send not subscribe answer: %w", err)
 		}
@@ -207,7 +246,7 @@ func (b *Bot) handleMessage(receivedMessage *tgbotapi.Message) error {
 		return nil
 
 	case newTorrentCommand:
-		err := b.handleNewTorrentCommand(userName, chatID)
+		err := b.handleNewTorrentCommand(userName, chatID, userID)
 		if err != nil {
 			return fmt.Errorf("b.handleNewTorrentCommand(%q, %d): %w", userName, chatID, err)
 		}
@@ -223,7 +262,7 @@ func (b *Bot) handleMessage(receivedMessage *tgbotapi.Message) error {
 
 	default:
 		if lastCommand := b.usersLastCommand[userName]; lastCommand == newTorrentCommand {
-			err := b.handleAddingNewTorrent(userName, chatID, receivedMessage.Text)
+			err := b.handleAddingNewTorrent(userName, chatID, userID, receivedMessage.Text)
 			if err != nil {
 				return fmt.Errorf("b.handleAddingNewTorrent(%q, %d, %q): %w", userName, chatID, receivedMessage.Text, err)
 			}
@@ -231,8 +270,7 @@ func (b *Bot) handleMessage(receivedMessage *tgbotapi.Message) error {
 			return nil
 		}
 
-		msg := tgbotapi.NewMessage(chatID, unknownCommandAnswer)
-		_, err := b.botAPI.Send(msg)
+		err := b.send(chatID, unknownCommandAnswer)
 		if err != nil {
 			return fmt.Errorf("cannot send unknown command answer: %w", err)
 		}
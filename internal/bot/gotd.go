@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gotd/td/telegram/message/html"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+
+	"github.com/aleksander-git/telegram-torrent/internal/gotdclient"
+)
+
+// GotdMessenger is a Messenger using MTProto client of gotd,
+// so the same connection and session can be used for uploads
+type GotdMessenger struct {
+	client   *gotdclient.Client
+	resolver *gotdclient.Resolver
+
+	messages chan Message
+}
+
+var _ Messenger = (*GotdMessenger)(nil)
+
+// NewGotdMessenger registers handlers of new messages in the dispatcher.
+// The dispatcher must be passed to the client by gotdclient.WithUpdateHandler
+// and the messenger must be created before the client is connected
+func NewGotdMessenger(client *gotdclient.Client, dispatcher tg.UpdateDispatcher, resolver *gotdclient.Resolver) *GotdMessenger {
+	m := &GotdMessenger{
+		client:   client,
+		resolver: resolver,
+		messages: make(chan Message, 100),
+	}
+
+	dispatcher.OnNewMessage(func(ctx context.Context, e tg.Entities, update *tg.UpdateNewMessage) error {
+		return m.receive(ctx, e, update.Message)
+	})
+	dispatcher.OnNewChannelMessage(func(ctx context.Context, e tg.Entities, update *tg.UpdateNewChannelMessage) error {
+		return m.receive(ctx, e, update.Message)
+	})
+
+	return m
+}
+
+func (m *GotdMessenger) UserName(ctx context.Context) (string, error) {
+	self, err := m.client.Telegram().Self(ctx)
+	if err != nil {
+		return "", fmt.Errorf("m.client.Telegram().Self(ctx): %w", err)
+	}
+
+	return self.Username, nil
+}
+
+func (m *GotdMessenger) Listen(ctx context.Context, handle func(ctx context.Context, msg Message)) error {
+	// server starts sending updates to the bot after the state is requested
+	if _, err := m.client.API().UpdatesGetState(ctx); err != nil {
+		return fmt.Errorf("m.client.API().UpdatesGetState(ctx): %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-m.messages:
+			handle(ctx, msg)
+		}
+	}
+}
+
+// receive passes the incoming message to Listen and remembers its peers to answer
+func (m *GotdMessenger) receive(ctx context.Context, e tg.Entities, msgClass tg.MessageClass) error {
+	msg, ok := msgClass.(*tg.Message)
+	if !ok || msg.Out {
+		return nil
+	}
+
+	from, ok := msg.GetFromID()
+	if !ok {
+		// messages in private chats have no sender, it's the chat
+		from = msg.PeerID
+	}
+
+	fromUser, ok := from.(*tg.PeerUser)
+	if !ok {
+		return nil
+	}
+
+	m.resolver.RememberEntities(e.Users, e.Channels)
+
+	received := Message{
+		ChatID: gotdclient.BotAPIID(msg.PeerID),
+		UserID: fromUser.UserID,
+		Text:   msg.Message,
+	}
+
+	if user, ok := e.Users[fromUser.UserID]; ok {
+		received.UserName = user.Username
+		m.resolver.Remember(strconv.FormatInt(user.ID, 10), user.AsInputPeer())
+	}
+
+	switch peer := msg.PeerID.(type) {
+	case *tg.PeerChat:
+		m.resolver.Remember(strconv.FormatInt(received.ChatID, 10), &tg.InputPeerChat{ChatID: peer.ChatID})
+	case *tg.PeerChannel:
+		if channel, ok := e.Channels[peer.ChannelID]; ok {
+			m.resolver.Remember(strconv.FormatInt(received.ChatID, 10), channel.AsInputPeer())
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case m.messages <- received:
+		return nil
+	}
+}
+
+func (m *GotdMessenger) Send(ctx context.Context, msg OutgoingMessage) error {
+	builder := m.resolver.Resolve(strconv.FormatInt(msg.ChatID, 10))
+
+	var err error
+	switch msg.ParseMode {
+	case ParseModeHTML:
+		_, err = builder.StyledText(ctx, html.String(nil, msg.Text))
+	case ParseModeMarkdown:
+		_, err = builder.StyledText(ctx, markdown(msg.Text)...)
+	default:
+		_, err = builder.Text(ctx, msg.Text)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send message to chat %d: %w", msg.ChatID, err)
+	}
+
+	return nil
+}
+
+func (m *GotdMessenger) IsMember(ctx context.Context, chatID int64, userID int64) (bool, error) {
+	chatPeer, err := m.resolver.ResolvePeer(ctx, strconv.FormatInt(chatID, 10))
+	if err != nil {
+		return false, fmt.Errorf("m.resolver.ResolvePeer(ctx, %d): %w", chatID, err)
+	}
+
+	channel, ok := chatPeer.(*tg.InputPeerChannel)
+	if !ok {
+		return false, errors.New("membership can be checked only in channels and supergroups")
+	}
+
+	userPeer, err := m.resolver.ResolvePeer(ctx, strconv.FormatInt(userID, 10))
+	if err != nil {
+		return false, fmt.Errorf("m.resolver.ResolvePeer(ctx, %d): %w", userID, err)
+	}
+
+	participant, err := m.client.API().ChannelsGetParticipant(ctx, &tg.ChannelsGetParticipantRequest{
+		Channel:     &tg.InputChannel{ChannelID: channel.ChannelID, AccessHash: channel.AccessHash},
+		Participant: userPeer,
+	})
+	if tgerr.Is(err, "USER_NOT_PARTICIPANT") {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ChannelsGetParticipant(ctx, %d, %d): %w", chatID, userID, err)
+	}
+
+	switch p := participant.Participant.(type) {
+	case *tg.ChannelParticipantLeft:
+		return false, nil
+	case *tg.ChannelParticipantBanned:
+		// restricted users are still members unless they are kicked
+		return !p.Left && !p.BannedRights.ViewMessages, nil
+	default:
+		return true, nil
+	}
+}
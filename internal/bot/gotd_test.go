@@ -0,0 +1,83 @@
+package bot_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/bot"
+	"github.com/aleksander-git/telegram-torrent/internal/gotdclient"
+)
+
+// invoker records the sent messages
+type invoker struct {
+	sent []*tg.MessagesSendMessageRequest
+}
+
+func (i *invoker) Invoke(_ context.Context, input bin.Encoder, output bin.Decoder) error {
+	request, ok := input.(*tg.MessagesSendMessageRequest)
+	if !ok {
+		return errors.New("unexpected request")
+	}
+	i.sent = append(i.sent, request)
+
+	var buf bin.Buffer
+	if err := (&tg.Updates{}).Encode(&buf); err != nil {
+		return err
+	}
+
+	return output.Decode(&buf)
+}
+
+func TestGotdMessenger_Send(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      bot.OutgoingMessage
+		text     string
+		entities []tg.MessageEntityClass
+	}{
+		{
+			name: "plain",
+			msg:  bot.OutgoingMessage{Text: "*not bold*"},
+			text: "*not bold*",
+		}, {
+			name: "markdown",
+			msg: bot.OutgoingMessage{
+				Text:      "*Торренты:*\n\\_file _name_ `done` [link](https://example.com) 2*2\n```\npre```",
+				ParseMode: bot.ParseModeMarkdown,
+			},
+			text: "Торренты:\n_file name done link 2*2\npre",
+			entities: []tg.MessageEntityClass{
+				&tg.MessageEntityBold{Offset: 0, Length: 9},
+				&tg.MessageEntityItalic{Offset: 16, Length: 4},
+				&tg.MessageEntityCode{Offset: 21, Length: 4},
+				&tg.MessageEntityTextURL{Offset: 26, Length: 4, URL: "https://example.com"},
+				&tg.MessageEntityPre{Offset: 35, Length: 3},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			invoker := &invoker{}
+			api := tg.NewClient(invoker)
+
+			resolver := gotdclient.NewResolver(api, message.NewSender(api))
+			resolver.Remember("42", &tg.InputPeerUser{UserID: 42})
+
+			messenger := bot.NewGotdMessenger(nil, tg.NewUpdateDispatcher(), resolver)
+
+			test.msg.ChatID = 42
+			require.NoError(t, messenger.Send(context.Background(), test.msg))
+
+			require.Len(t, invoker.sent, 1)
+			require.Equal(t, test.text, invoker.sent[0].Message)
+			require.Equal(t, test.entities, invoker.sent[0].Entities)
+		})
+	}
+}
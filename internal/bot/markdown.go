@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/gotd/td/telegram/message/styling"
+)
+
+// markdownEscaped are characters escaped with backslash outside of entities
+const markdownEscaped = "_*`["
+
+// markdown converts text in the legacy Markdown of Bot API to the styled text:
+// *bold*, _italic_, `code`, ```pre``` and [text](url). Entities aren't nested,
+// unclosed ones are kept as plain text
+func markdown(text string) []styling.StyledTextOption {
+	var (
+		options []styling.StyledTextOption
+		plain   strings.Builder
+	)
+
+	add := func(option styling.StyledTextOption) {
+		if plain.Len() > 0 {
+			options = append(options, styling.Plain(plain.String()))
+			plain.Reset()
+		}
+		options = append(options, option)
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.IndexByte(markdownEscaped, rest[1]) >= 0:
+			plain.WriteByte(rest[1])
+			i += 2
+			continue
+
+		case strings.HasPrefix(rest, "```"):
+			if end := strings.Index(rest[3:], "```"); end > 0 {
+				add(styling.Pre(strings.TrimPrefix(rest[3:3+end], "\n"), ""))
+				i += 3 + end + 3
+				continue
+			}
+
+		case rest[0] == '*' || rest[0] == '_' || rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 {
+				entity := rest[1 : 1+end]
+				switch rest[0] {
+				case '*':
+					add(styling.Bold(entity))
+				case '_':
+					add(styling.Italic(entity))
+				default:
+					add(styling.Code(entity))
+				}
+				i += 1 + end + 1
+				continue
+			}
+
+		case rest[0] == '[':
+			if end := strings.Index(rest, "]("); end > 1 {
+				if urlEnd := strings.IndexByte(rest[end+2:], ')'); urlEnd > 0 {
+					add(styling.TextURL(rest[1:end], rest[end+2:end+2+urlEnd]))
+					i += end + 2 + urlEnd + 1
+					continue
+				}
+			}
+		}
+
+		plain.WriteByte(rest[0])
+		i++
+	}
+
+	if plain.Len() > 0 {
+		options = append(options, styling.Plain(plain.String()))
+	}
+
+	return options
+}
@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"context"
+)
+
+// ParseMode is a formatting of the message text
+type ParseMode string
+
+const (
+	ParseModeNone ParseMode = ""
+	ParseModeHTML ParseMode = "HTML"
+	// ParseModeMarkdown is the legacy Markdown of Bot API
+	ParseModeMarkdown ParseMode = "Markdown"
+)
+
+// Message is a message received by the bot
+type Message struct {
+	// ChatID is a Bot API ID of the chat, e.g. -1002184825487 for channels and supergroups
+	ChatID   int64
+	UserID   int64
+	UserName string
+	Text     string
+}
+
+// OutgoingMessage is a message sent by the bot
+type OutgoingMessage struct {
+	ChatID    int64
+	Text      string
+	ParseMode ParseMode
+}
+
+// Messenger receives and sends messages of the bot through a Telegram client
+type Messenger interface {
+	// UserName returns username of the bot
+	UserName(ctx context.Context) (string, error)
+	// Listen calls handle for every message received by the bot until ctx is done
+	Listen(ctx context.Context, handle func(ctx context.Context, msg Message)) error
+	Send(ctx context.Context, msg OutgoingMessage) error
+	// IsMember reports whether the user is a member of the chat
+	IsMember(ctx context.Context, chatID int64, userID int64) (bool, error)
+}
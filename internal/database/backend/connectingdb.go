@@ -3,6 +3,8 @@ package backend
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -23,6 +25,11 @@ func NewDatabase(connectionString string) (*Database, error) {
 		return nil, err
 	}
 
+	if err := Migrate(context.Background(), db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Migrate(): %w", err)
+	}
+
 	return &Database{
 		Queries: New(db),
 		db:      db,
@@ -42,10 +49,105 @@ func (d *Database) GetUser(ctx context.Context, id int64) (User, error) {
 	return d.Queries.GetUser(ctx, id)
 }
 
-func (d *Database) AddTorrent(ctx context.Context, arg AddTorrentParams) error {
+// AddTorrent adds the torrent if it's new and returns its ID
+func (d *Database) AddTorrent(ctx context.Context, arg AddTorrentParams) (int64, error) {
 	return d.Queries.AddTorrent(ctx, arg)
 }
 
+// AddUserTorrent adds the torrent if it's new and binds it to the user in one transaction.
+// The user is created if it doesn't exist. Timestamp columns have no time zone, timeAdded is stored in UTC
+func (d *Database) AddUserTorrent(ctx context.Context, userID int64, chatID int64, torrentLink string, timeAdded time.Time) error {
+	timeAdded = timeAdded.UTC()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("d.db.BeginTx(): %w", err)
+	}
+	defer tx.Rollback()
+
+	q := d.Queries.WithTx(tx)
+
+	if err := q.EnsureUser(ctx, EnsureUserParams{ID: userID, ChatID: chatID}); err != nil {
+		return fmt.Errorf("q.EnsureUser(ctx, %d): %w", userID, err)
+	}
+
+	torrentID, err := q.AddTorrent(ctx, AddTorrentParams{TorrentLink: torrentLink, TimeAdded: timeAdded})
+	if err != nil {
+		return fmt.Errorf("q.AddTorrent(ctx, %q): %w", torrentLink, err)
+	}
+
+	params := AddTorrentXUserParams{
+		TorrentID: torrentID,
+		UserID:    userID,
+		TimeAdded: timeAdded,
+	}
+	if err := q.AddTorrentXUser(ctx, params); err != nil {
+		return fmt.Errorf("q.AddTorrentXUser(ctx, %d, %d): %w", torrentID, userID, err)
+	}
+
+	return tx.Commit()
+}
+
+// GetUserTorrentTimes returns times the user added torrents after since, the oldest first
+func (d *Database) GetUserTorrentTimes(ctx context.Context, userID int64, since time.Time) ([]time.Time, error) {
+	params := GetUserTorrentTimesParams{
+		UserID:    userID,
+		TimeAdded: since,
+	}
+	return d.Queries.GetUserTorrentTimes(ctx, params)
+}
+
+// GetUserTorrentBytes returns total size of torrents the user added after since.
+// Rejected torrents and the torrent torrentLink aren't counted
+func (d *Database) GetUserTorrentBytes(ctx context.Context, userID int64, since time.Time, torrentLink string) (int64, error) {
+	params := GetUserTorrentBytesParams{
+		UserID:      userID,
+		TimeAdded:   since,
+		TorrentLink: torrentLink,
+	}
+	return d.Queries.GetUserTorrentBytes(ctx, params)
+}
+
+// GetTorrentNames returns torrents whose names are known from metadata
+func (d *Database) GetTorrentNames(ctx context.Context) ([]GetTorrentNamesRow, error) {
+	return d.Queries.GetTorrentNames(ctx)
+}
+
+// GetTorrentUserIDs returns IDs of the users who added the torrent, the first one first
+func (d *Database) GetTorrentUserIDs(ctx context.Context, torrentLink string) ([]int64, error) {
+	return d.Queries.GetTorrentUserIDs(ctx, torrentLink)
+}
+
+// UpdateTorrentInfo stores name and size of the torrent known from its metadata
+func (d *Database) UpdateTorrentInfo(ctx context.Context, torrentLink string, name string, size int64) error {
+	nameParams := UpdateTorrentNameParams{
+		TorrentLink: torrentLink,
+		Name:        sql.NullString{String: name, Valid: name != ""},
+	}
+	if err := d.Queries.UpdateTorrentName(ctx, nameParams); err != nil {
+		return fmt.Errorf("d.Queries.UpdateTorrentName(ctx, %q): %w", torrentLink, err)
+	}
+
+	sizeParams := UpdateTorrentSizeParams{
+		TorrentLink: torrentLink,
+		Size:        sql.NullInt64{Int64: size, Valid: true},
+	}
+	if err := d.Queries.UpdateTorrentSize(ctx, sizeParams); err != nil {
+		return fmt.Errorf("d.Queries.UpdateTorrentSize(ctx, %q): %w", torrentLink, err)
+	}
+
+	return nil
+}
+
+// UpdateTorrentError stores the reason the torrent failed
+func (d *Database) UpdateTorrentError(ctx context.Context, torrentLink string, reason string) error {
+	params := UpdateTorrentErrorParams{
+		TorrentLink: torrentLink,
+		Error:       sql.NullString{String: reason, Valid: reason != ""},
+	}
+	return d.Queries.UpdateTorrentError(ctx, params)
+}
+
 func (d *Database) GetTorrent(ctx context.Context, torrentLink string) (Torrent, error) {
 	return d.Queries.GetTorrent(ctx, torrentLink)
 }
@@ -61,3 +163,73 @@ func (d *Database) GetSetting(ctx context.Context, userID int64, key string) (st
 	}
 	return d.Queries.GetSetting(ctx, params)
 }
+
+// SetSetting creates or replaces the setting value.
+// Zero userID sets the global value
+func (d *Database) SetSetting(ctx context.Context, userID int64, key string, value string) error {
+	params := SetSettingParams{
+		Name:   key,
+		Value:  value,
+		UserID: sql.NullInt64{Int64: userID, Valid: userID != 0},
+	}
+	return d.Queries.SetSetting(ctx, params)
+}
+
+// DeleteSetting removes the setting value, so the global or default one is used instead.
+// Zero userID removes the global value
+func (d *Database) DeleteSetting(ctx context.Context, userID int64, key string) error {
+	params := DeleteSettingParams{
+		Name:   key,
+		UserID: sql.NullInt64{Int64: userID, Valid: userID != 0},
+	}
+	return d.Queries.DeleteSetting(ctx, params)
+}
+
+// GetGlobalSettings returns all global (not bound to a user) settings
+// whose names start with prefix, keyed by setting name
+func (d *Database) GetGlobalSettings(ctx context.Context, prefix string) (map[string]string, error) {
+	rows, err := d.Queries.GetGlobalSettingsByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]string, len(rows))
+	for _, row := range rows {
+		settings[row.Name] = row.Value
+	}
+	return settings, nil
+}
+
+// UpdateTorrentTopicID stores ID of the forum topic the torrent files are posted in
+func (d *Database) UpdateTorrentTopicID(ctx context.Context, torrentLink string, topicID int64) error {
+	params := UpdateTorrentTopicIDParams{
+		TorrentLink: torrentLink,
+		TopicID:     sql.NullInt64{Int64: topicID, Valid: topicID != 0},
+	}
+	return d.Queries.UpdateTorrentTopicID(ctx, params)
+}
+
+// UpdateTorrentMessageID stores ID of the header post of the torrent
+func (d *Database) UpdateTorrentMessageID(ctx context.Context, torrentLink string, messageID int64) error {
+	params := UpdateTorrentMessageIDParams{
+		TorrentLink: torrentLink,
+		MessageID:   sql.NullInt64{Int64: messageID, Valid: messageID != 0},
+	}
+	return d.Queries.UpdateTorrentMessageID(ctx, params)
+}
+
+// GetTelegramSession returns data of the MTProto session stored by name.
+// sql.ErrNoRows is returned if there is no such session
+func (d *Database) GetTelegramSession(ctx context.Context, name string) ([]byte, error) {
+	return d.Queries.GetTelegramSession(ctx, name)
+}
+
+// SaveTelegramSession creates or replaces data of the MTProto session stored by name
+func (d *Database) SaveTelegramSession(ctx context.Context, name string, data []byte) error {
+	params := SaveTelegramSessionParams{
+		Name:        name,
+		Data:        data,
+		TimeUpdated: time.Now(),
+	}
+	return d.Queries.SaveTelegramSession(ctx, params)
+}
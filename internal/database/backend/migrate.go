@@ -0,0 +1,53 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// ErrSchemaTooNew is returned by Migrate if the database has migrations unknown to this build
+var ErrSchemaTooNew = errors.New("database schema is newer than the application")
+
+// Migrate applies pending embedded migrations.
+// The Postgres advisory lock keeps concurrent instances from migrating at the same time
+func Migrate(ctx context.Context, db *sql.DB) error {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return fmt.Errorf("fs.Sub(migrations, %q): %w", "migrations", err)
+	}
+
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return fmt.Errorf("lock.NewPostgresSessionLocker(): %w", err)
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, fsys, goose.WithSessionLocker(locker))
+	if err != nil {
+		return fmt.Errorf("goose.NewProvider(): %w", err)
+	}
+
+	current, target, err := provider.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("provider.GetVersions(ctx): %w", err)
+	}
+
+	if current > target {
+		return fmt.Errorf("%w: database version %d, latest known migration %d", ErrSchemaTooNew, current, target)
+	}
+
+	if _, err := provider.Up(ctx); err != nil {
+		return fmt.Errorf("provider.Up(ctx): %w", err)
+	}
+
+	return nil
+}
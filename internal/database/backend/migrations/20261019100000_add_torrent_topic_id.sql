@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE torrents
+  ADD COLUMN topic_id BIGINT DEFAULT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE torrents
+  DROP COLUMN topic_id;
+-- +goose StatementEnd
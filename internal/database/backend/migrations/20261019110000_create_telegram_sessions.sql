@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE telegram_sessions
+(
+  name         TEXT      NOT NULL,
+  data         BYTEA     NOT NULL,
+  time_updated TIMESTAMP NOT NULL,
+  PRIMARY KEY (name)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE telegram_sessions;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+DELETE FROM settings AS a
+USING settings AS b
+WHERE a.name = b.name
+  AND COALESCE(a.user_id, 0) = COALESCE(b.user_id, 0)
+  AND a.id < b.id;
+
+CREATE UNIQUE INDEX settings_name_user_id_key
+  ON settings (name, (COALESCE(user_id, 0)));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX settings_name_user_id_key;
+-- +goose StatementEnd
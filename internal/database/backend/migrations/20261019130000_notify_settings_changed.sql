@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION notify_settings_changed() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    PERFORM pg_notify('settings_changed', OLD.name);
+  ELSE
+    PERFORM pg_notify('settings_changed', NEW.name);
+  END IF;
+
+  IF TG_OP = 'UPDATE' AND OLD.name <> NEW.name THEN
+    PERFORM pg_notify('settings_changed', OLD.name);
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER settings_changed
+  AFTER INSERT OR UPDATE OR DELETE ON settings
+  FOR EACH ROW EXECUTE FUNCTION notify_settings_changed();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER settings_changed ON settings;
+
+DROP FUNCTION notify_settings_changed();
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE torrent_x_user
+  ADD COLUMN time_added TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC');
+
+CREATE INDEX torrent_x_user_user_id_time_added_idx
+  ON torrent_x_user (user_id, time_added);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX torrent_x_user_user_id_time_added_idx;
+
+ALTER TABLE torrent_x_user
+  DROP COLUMN time_added;
+-- +goose StatementEnd
@@ -16,6 +16,12 @@ type Setting struct {
 	UserID sql.NullInt64
 }
 
+type TelegramSession struct {
+	Name        string
+	Data        []byte
+	TimeUpdated time.Time
+}
+
 type Torrent struct {
 	ID           int64
 	MessageID    sql.NullInt64
@@ -26,12 +32,14 @@ type Torrent struct {
 	TimeStarted  sql.NullTime
 	TimeFinished sql.NullTime
 	Error        sql.NullString
+	TopicID      sql.NullInt64
 }
 
 type TorrentXUser struct {
 	TorrentID int64
 	UserID    int64
 	Sent      bool
+	TimeAdded time.Time
 }
 
 type User struct {
@@ -11,12 +11,15 @@ import (
 	"time"
 )
 
-const addTorrent = `-- name: AddTorrent :exec
+const addTorrent = `-- name: AddTorrent :one
 INSERT INTO torrents (
     torrent_link, time_added
 ) VALUES (
     $1, $2
 )
+ON CONFLICT (torrent_link) DO UPDATE
+    SET torrent_link = EXCLUDED.torrent_link
+RETURNING id
 `
 
 type AddTorrentParams struct {
@@ -24,26 +27,30 @@ type AddTorrentParams struct {
 	TimeAdded   time.Time
 }
 
-func (q *Queries) AddTorrent(ctx context.Context, arg AddTorrentParams) error {
-	_, err := q.db.ExecContext(ctx, addTorrent, arg.TorrentLink, arg.TimeAdded)
-	return err
+func (q *Queries) AddTorrent(ctx context.Context, arg AddTorrentParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, addTorrent, arg.TorrentLink, arg.TimeAdded)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
 }
 
 const addTorrentXUser = `-- name: AddTorrentXUser :exec
 INSERT INTO torrent_x_user (
-    torrent_id, user_id
+    torrent_id, user_id, time_added
 ) VALUES (
-    $1, $2
+    $1, $2, $3
 )
+ON CONFLICT (torrent_id, user_id) DO NOTHING
 `
 
 type AddTorrentXUserParams struct {
 	TorrentID int64
 	UserID    int64
+	TimeAdded time.Time
 }
 
 func (q *Queries) AddTorrentXUser(ctx context.Context, arg AddTorrentXUserParams) error {
-	_, err := q.db.ExecContext(ctx, addTorrentXUser, arg.TorrentID, arg.UserID)
+	_, err := q.db.ExecContext(ctx, addTorrentXUser, arg.TorrentID, arg.UserID, arg.TimeAdded)
 	return err
 }
 
@@ -66,8 +73,43 @@ func (q *Queries) AddUser(ctx context.Context, arg AddUserParams) error {
 	return err
 }
 
+const deleteSetting = `-- name: DeleteSetting :exec
+DELETE FROM settings
+WHERE name = $1 AND user_id IS NOT DISTINCT FROM $2
+`
+
+type DeleteSettingParams struct {
+	Name   string
+	UserID sql.NullInt64
+}
+
+func (q *Queries) DeleteSetting(ctx context.Context, arg DeleteSettingParams) error {
+	_, err := q.db.ExecContext(ctx, deleteSetting, arg.Name, arg.UserID)
+	return err
+}
+
+const ensureUser = `-- name: EnsureUser :exec
+INSERT INTO users (
+    id, chat_id
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (id) DO UPDATE
+    SET chat_id = EXCLUDED.chat_id
+`
+
+type EnsureUserParams struct {
+	ID     int64
+	ChatID int64
+}
+
+func (q *Queries) EnsureUser(ctx context.Context, arg EnsureUserParams) error {
+	_, err := q.db.ExecContext(ctx, ensureUser, arg.ID, arg.ChatID)
+	return err
+}
+
 const getFirstUnstartedTorrent = `-- name: GetFirstUnstartedTorrent :one
-SELECT t.id, t.message_id, t.torrent_link, t.name, t.size, t.time_added, t.time_started, t.time_finished, t.error
+SELECT t.id, t.message_id, t.torrent_link, t.name, t.size, t.time_added, t.time_started, t.time_finished, t.error, t.topic_id
 FROM torrents AS t
 INNER JOIN torrent_x_user AS txu
     ON t.id = txu.torrent_id
@@ -91,14 +133,50 @@ func (q *Queries) GetFirstUnstartedTorrent(ctx context.Context) (Torrent, error)
 		&i.TimeStarted,
 		&i.TimeFinished,
 		&i.Error,
+		&i.TopicID,
 	)
 	return i, err
 }
 
+const getGlobalSettingsByPrefix = `-- name: GetGlobalSettingsByPrefix :many
+SELECT id, name, value, user_id
+FROM settings
+WHERE user_id IS NULL AND starts_with(name, $1::text)
+ORDER BY name ASC
+`
+
+func (q *Queries) GetGlobalSettingsByPrefix(ctx context.Context, prefix string) ([]Setting, error) {
+	rows, err := q.db.QueryContext(ctx, getGlobalSettingsByPrefix, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Setting
+	for rows.Next() {
+		var i Setting
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Value,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getSetting = `-- name: GetSetting :one
 SELECT value
 FROM settings
-WHERE name = $1 AND (user_id == $2 OR user_id IS NULL)
+WHERE name = $1 AND (user_id = $2 OR user_id IS NULL)
 ORDER BY user_id ASC NULLS LAST
 LIMIT 1
 `
@@ -115,8 +193,21 @@ func (q *Queries) GetSetting(ctx context.Context, arg GetSettingParams) (string,
 	return value, err
 }
 
+const getTelegramSession = `-- name: GetTelegramSession :one
+SELECT data
+FROM telegram_sessions
+WHERE name = $1
+`
+
+func (q *Queries) GetTelegramSession(ctx context.Context, name string) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getTelegramSession, name)
+	var data []byte
+	err := row.Scan(&data)
+	return data, err
+}
+
 const getTorrent = `-- name: GetTorrent :one
-SELECT id, message_id, torrent_link, name, size, time_added, time_started, time_finished, error
+SELECT id, message_id, torrent_link, name, size, time_added, time_started, time_finished, error, topic_id
 FROM torrents
 WHERE torrent_link = $1
 `
@@ -134,10 +225,84 @@ func (q *Queries) GetTorrent(ctx context.Context, torrentLink string) (Torrent,
 		&i.TimeStarted,
 		&i.TimeFinished,
 		&i.Error,
+		&i.TopicID,
 	)
 	return i, err
 }
 
+const getTorrentNames = `-- name: GetTorrentNames :many
+SELECT torrent_link, name, time_finished, error
+FROM torrents
+WHERE name IS NOT NULL
+`
+
+type GetTorrentNamesRow struct {
+	TorrentLink  string
+	Name         sql.NullString
+	TimeFinished sql.NullTime
+	Error        sql.NullString
+}
+
+func (q *Queries) GetTorrentNames(ctx context.Context) ([]GetTorrentNamesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTorrentNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTorrentNamesRow
+	for rows.Next() {
+		var i GetTorrentNamesRow
+		if err := rows.Scan(
+			&i.TorrentLink,
+			&i.Name,
+			&i.TimeFinished,
+			&i.Error,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTorrentUserIDs = `-- name: GetTorrentUserIDs :many
+SELECT txu.user_id
+FROM torrent_x_user AS txu
+INNER JOIN torrents AS t
+    ON txu.torrent_id = t.id
+WHERE t.torrent_link = $1
+ORDER BY txu.time_added ASC
+`
+
+func (q *Queries) GetTorrentUserIDs(ctx context.Context, torrentLink string) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getTorrentUserIDs, torrentLink)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var user_id int64
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUnsentUsersForTorrent = `-- name: GetUnsentUsersForTorrent :many
 SELECT 
     u.id, u.chat_id, u.priority
@@ -184,9 +349,65 @@ func (q *Queries) GetUser(ctx context.Context, id int64) (User, error) {
 	return i, err
 }
 
+const getUserTorrentBytes = `-- name: GetUserTorrentBytes :one
+SELECT COALESCE(SUM(t.size), 0)::BIGINT AS bytes
+FROM torrent_x_user AS txu
+INNER JOIN torrents AS t
+    ON txu.torrent_id = t.id
+WHERE txu.user_id = $1 AND txu.time_added > $2 AND t.error IS NULL AND t.torrent_link <> $3
+`
+
+type GetUserTorrentBytesParams struct {
+	UserID      int64
+	TimeAdded   time.Time
+	TorrentLink string
+}
+
+func (q *Queries) GetUserTorrentBytes(ctx context.Context, arg GetUserTorrentBytesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getUserTorrentBytes, arg.UserID, arg.TimeAdded, arg.TorrentLink)
+	var bytes int64
+	err := row.Scan(&bytes)
+	return bytes, err
+}
+
+const getUserTorrentTimes = `-- name: GetUserTorrentTimes :many
+SELECT time_added
+FROM torrent_x_user
+WHERE user_id = $1 AND time_added > $2
+ORDER BY time_added ASC
+`
+
+type GetUserTorrentTimesParams struct {
+	UserID    int64
+	TimeAdded time.Time
+}
+
+func (q *Queries) GetUserTorrentTimes(ctx context.Context, arg GetUserTorrentTimesParams) ([]time.Time, error) {
+	rows, err := q.db.QueryContext(ctx, getUserTorrentTimes, arg.UserID, arg.TimeAdded)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var time_added time.Time
+		if err := rows.Scan(&time_added); err != nil {
+			return nil, err
+		}
+		items = append(items, time_added)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserTorrents = `-- name: GetUserTorrents :many
 SELECT 
-    t.id, t.message_id, t.torrent_link, t.name, t.size, t.time_added, t.time_started, t.time_finished, t.error
+    t.id, t.message_id, t.torrent_link, t.name, t.size, t.time_added, t.time_started, t.time_finished, t.error, t.topic_id
 FROM torrent_x_user AS txu
 INNER JOIN torrents AS t
     ON txu.torrent_id = t.id
@@ -212,6 +433,7 @@ func (q *Queries) GetUserTorrents(ctx context.Context, userID int64) ([]Torrent,
 			&i.TimeStarted,
 			&i.TimeFinished,
 			&i.Error,
+			&i.TopicID,
 		); err != nil {
 			return nil, err
 		}
@@ -226,6 +448,65 @@ func (q *Queries) GetUserTorrents(ctx context.Context, userID int64) ([]Torrent,
 	return items, nil
 }
 
+const saveTelegramSession = `-- name: SaveTelegramSession :exec
+INSERT INTO telegram_sessions (
+    name, data, time_updated
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (name) DO UPDATE
+    SET data = EXCLUDED.data,
+    time_updated = EXCLUDED.time_updated
+`
+
+type SaveTelegramSessionParams struct {
+	Name        string
+	Data        []byte
+	TimeUpdated time.Time
+}
+
+func (q *Queries) SaveTelegramSession(ctx context.Context, arg SaveTelegramSessionParams) error {
+	_, err := q.db.ExecContext(ctx, saveTelegramSession, arg.Name, arg.Data, arg.TimeUpdated)
+	return err
+}
+
+const setSetting = `-- name: SetSetting :exec
+INSERT INTO settings (
+    name, value, user_id
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (name, (COALESCE(user_id, 0))) DO UPDATE
+    SET value = EXCLUDED.value
+`
+
+type SetSettingParams struct {
+	Name   string
+	Value  string
+	UserID sql.NullInt64
+}
+
+func (q *Queries) SetSetting(ctx context.Context, arg SetSettingParams) error {
+	_, err := q.db.ExecContext(ctx, setSetting, arg.Name, arg.Value, arg.UserID)
+	return err
+}
+
+const updateTorrentError = `-- name: UpdateTorrentError :exec
+UPDATE torrents
+    SET error = $2
+WHERE torrent_link = $1
+`
+
+type UpdateTorrentErrorParams struct {
+	TorrentLink string
+	Error       sql.NullString
+}
+
+func (q *Queries) UpdateTorrentError(ctx context.Context, arg UpdateTorrentErrorParams) error {
+	_, err := q.db.ExecContext(ctx, updateTorrentError, arg.TorrentLink, arg.Error)
+	return err
+}
+
 const updateTorrentMessageID = `-- name: UpdateTorrentMessageID :exec
 UPDATE torrents
     SET message_id = $2
@@ -299,6 +580,22 @@ func (q *Queries) UpdateTorrentStatus(ctx context.Context, arg UpdateTorrentStat
 	return err
 }
 
+const updateTorrentTopicID = `-- name: UpdateTorrentTopicID :exec
+UPDATE torrents
+    SET topic_id = $2
+WHERE torrent_link = $1
+`
+
+type UpdateTorrentTopicIDParams struct {
+	TorrentLink string
+	TopicID     sql.NullInt64
+}
+
+func (q *Queries) UpdateTorrentTopicID(ctx context.Context, arg UpdateTorrentTopicIDParams) error {
+	_, err := q.db.ExecContext(ctx, updateTorrentTopicID, arg.TorrentLink, arg.TopicID)
+	return err
+}
+
 const updateTorrentXUser = `-- name: UpdateTorrentXUser :exec
 UPDATE torrent_x_user
     SET sent = $3
@@ -5,6 +5,15 @@ INSERT INTO users (
     $1, $2, $3
 );
 
+-- name: EnsureUser :exec
+INSERT INTO users (
+    id, chat_id
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (id) DO UPDATE
+    SET chat_id = EXCLUDED.chat_id;
+
 -- name: GetUser :one
 SELECT * FROM users
 WHERE id = $1;
@@ -14,12 +23,15 @@ UPDATE users
     SET priority = $2
 WHERE id = $1;
 
--- name: AddTorrent :exec
+-- name: AddTorrent :one
 INSERT INTO torrents (
     torrent_link, time_added
 ) VALUES (
     $1, $2
-);
+)
+ON CONFLICT (torrent_link) DO UPDATE
+    SET torrent_link = EXCLUDED.torrent_link
+RETURNING id;
 
 -- name: GetTorrent :one
 SELECT *
@@ -37,6 +49,11 @@ WHERE time_started = NULL
 ORDER BY time_added ASC, u.priority DESC
 LIMIT 1;
 
+-- name: GetTorrentNames :many
+SELECT torrent_link, name, time_finished, error
+FROM torrents
+WHERE name IS NOT NULL;
+
 -- name: GetUserTorrents :many
 SELECT 
     t.*
@@ -50,6 +67,11 @@ UPDATE torrents
     SET message_id = $2
 WHERE torrent_link = $1;
 
+-- name: UpdateTorrentTopicID :exec
+UPDATE torrents
+    SET topic_id = $2
+WHERE torrent_link = $1;
+
 -- name: UpdateTorrentName :exec
 UPDATE torrents
     SET name = $2
@@ -60,6 +82,11 @@ UPDATE torrents
     SET size = $2
 WHERE torrent_link = $1;
 
+-- name: UpdateTorrentError :exec
+UPDATE torrents
+    SET error = $2
+WHERE torrent_link = $1;
+
 -- name: UpdateTorrentStatus :exec
 UPDATE torrents
     SET time_started = $2, 
@@ -69,10 +96,32 @@ WHERE torrent_link = $1;
 
 -- name: AddTorrentXUser :exec
 INSERT INTO torrent_x_user (
-    torrent_id, user_id
+    torrent_id, user_id, time_added
 ) VALUES (
-    $1, $2
-);
+    $1, $2, $3
+)
+ON CONFLICT (torrent_id, user_id) DO NOTHING;
+
+-- name: GetUserTorrentBytes :one
+SELECT COALESCE(SUM(t.size), 0)::BIGINT AS bytes
+FROM torrent_x_user AS txu
+INNER JOIN torrents AS t
+    ON txu.torrent_id = t.id
+WHERE txu.user_id = $1 AND txu.time_added > $2 AND t.error IS NULL AND t.torrent_link <> $3;
+
+-- name: GetTorrentUserIDs :many
+SELECT txu.user_id
+FROM torrent_x_user AS txu
+INNER JOIN torrents AS t
+    ON txu.torrent_id = t.id
+WHERE t.torrent_link = $1
+ORDER BY txu.time_added ASC;
+
+-- name: GetUserTorrentTimes :many
+SELECT time_added
+FROM torrent_x_user
+WHERE user_id = $1 AND time_added > $2
+ORDER BY time_added ASC;
 
 -- name: UpdateTorrentXUser :exec
 UPDATE torrent_x_user
@@ -92,6 +141,40 @@ WHERE t.torrent_link = $1 AND u.sent = FALSE;
 -- name: GetSetting :one
 SELECT value
 FROM settings
-WHERE name = $1 AND (user_id == $2 OR user_id IS NULL)
+WHERE name = $1 AND (user_id = $2 OR user_id IS NULL)
 ORDER BY user_id ASC NULLS LAST
 LIMIT 1;
+
+-- name: SetSetting :exec
+INSERT INTO settings (
+    name, value, user_id
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (name, (COALESCE(user_id, 0))) DO UPDATE
+    SET value = EXCLUDED.value;
+
+-- name: DeleteSetting :exec
+DELETE FROM settings
+WHERE name = $1 AND user_id IS NOT DISTINCT FROM $2;
+
+-- name: GetGlobalSettingsByPrefix :many
+SELECT *
+FROM settings
+WHERE user_id IS NULL AND starts_with(name, sqlc.arg(prefix)::text)
+ORDER BY name ASC;
+
+-- name: GetTelegramSession :one
+SELECT data
+FROM telegram_sessions
+WHERE name = $1;
+
+-- name: SaveTelegramSession :exec
+INSERT INTO telegram_sessions (
+    name, data, time_updated
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (name) DO UPDATE
+    SET data = EXCLUDED.data,
+    time_updated = EXCLUDED.time_updated;
@@ -1,7 +1,9 @@
 package database_test
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -9,6 +11,8 @@ import (
 
 	_ "github.com/lib/pq"
 	"github.com/pressly/goose/v3"
+
+	"github.com/aleksander-git/telegram-torrent/internal/database/backend"
 )
 
 const (
@@ -99,3 +103,33 @@ func TestMigration(t *testing.T) {
 	}
 	t.Log("migrations were successfully rolled back")
 }
+
+func TestEmbeddedMigration(t *testing.T) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("HOST"), os.Getenv("PORT"), os.Getenv("USER"), os.Getenv("PASSWORD"), os.Getenv("DB_NAME"))
+
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		t.Fatalf("cannot open db: %s", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	// applying twice must be a no-op the second time
+	for i := 0; i < 2; i++ {
+		if err := backend.Migrate(ctx, db); err != nil {
+			t.Fatalf("cannot apply embedded migrations: %s", err)
+		}
+	}
+
+	const futureVersion = 99990101000000
+	if _, err := db.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, true)", futureVersion); err != nil {
+		t.Fatalf("cannot insert future version: %s", err)
+	}
+	defer db.Exec("DELETE FROM goose_db_version WHERE version_id = $1", futureVersion)
+
+	if err := backend.Migrate(ctx, db); !errors.Is(err, backend.ErrSchemaTooNew) {
+		t.Fatalf("expected %q, got: %v", backend.ErrSchemaTooNew, err)
+	}
+}
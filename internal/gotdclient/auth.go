@@ -0,0 +1,90 @@
+package gotdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+)
+
+// CodeProvider provides the login code Telegram sent to the user account
+type CodeProvider interface {
+	// Code returns the login code sent to the phone. Non-digit characters are ignored,
+	// so the code can be typed like "1 2 3 4 5": Telegram expires codes shared in plain
+	Code(ctx context.Context, phone string) (string, error)
+}
+
+// CodeProviderFunc is a function implementing CodeProvider
+type CodeProviderFunc func(ctx context.Context, phone string) (string, error)
+
+func (f CodeProviderFunc) Code(ctx context.Context, phone string) (string, error) {
+	return f(ctx, phone)
+}
+
+// UserCredentials are credentials of the user account
+type UserCredentials struct {
+	Phone string
+	// Password is a 2FA password, it's required only if 2FA is enabled for the account
+	Password string
+	Codes    CodeProvider
+}
+
+// ConnectUser connects client and logs in the user account.
+//
+// The client is stopped if the login fails.
+// The login is skipped if the session loaded from the storage is already authorized,
+// use WithSessionStorage to keep the session across restarts
+func (c *Client) ConnectUser(ctx context.Context, credentials UserCredentials) error {
+	if credentials.Codes == nil {
+		return errors.New("login code provider is required")
+	}
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+
+	codeAuth := auth.CodeAuthenticatorFunc(func(ctx context.Context, _ *tg.AuthSentCode) (string, error) {
+		code, err := credentials.Codes.Code(ctx, credentials.Phone)
+		if err != nil {
+			return "", fmt.Errorf("failed to get login code: %w", err)
+		}
+
+		return digits(code), nil
+	})
+
+	userAuth := auth.CodeOnly(credentials.Phone, codeAuth)
+	if credentials.Password != "" {
+		userAuth = auth.Constant(credentials.Phone, credentials.Password, codeAuth)
+	}
+
+	flow := auth.NewFlow(userAuth, auth.SendCodeOptions{})
+	if err := c.Telegram().Auth().IfNecessary(ctx, flow); err != nil {
+		_ = c.Close()
+		return fmt.Errorf("failed to auth user %q: %w", maskPhone(credentials.Phone), err)
+	}
+
+	return nil
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// maskPhone hides all the digits of the phone but the last 4 ones to keep it out of logs
+func maskPhone(phone string) string {
+	phone = digits(phone)
+	if len(phone) <= 4 {
+		return phone
+	}
+
+	return strings.Repeat("*", len(phone)-4) + phone[len(phone)-4:]
+}
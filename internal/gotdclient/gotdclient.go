@@ -4,47 +4,119 @@ package gotdclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gotd/contrib/bg"
 	"github.com/gotd/td/bin"
+	"github.com/gotd/td/session"
 	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
 )
 
 type Client struct {
-	Client *telegram.Client
+	log         *slog.Logger
+	floodWaiter *FloodWaiter
 
-	stop func() error
+	pingInterval time.Duration
+	pingTimeout  time.Duration
+
+	// state is the current State
+	state  atomic.Int64
+	states chan State
+
+	// newTelegram creates telegram.Client with the options of the client.
+	// Stopped telegram.Client can't run again, so a new one is created on every reconnect
+	newTelegram func() *telegram.Client
+	// run runs telegram.Client in background until stop is called or ctx is done
+	run func(ctx context.Context, client *telegram.Client) (stop func() error, err error)
+	// pinger pings the server with telegram.Client
+	pinger func(ctx context.Context, client *telegram.Client) error
+
+	mu     sync.Mutex
+	client *telegram.Client
+	stop   func() error
+	// stopSupervisor stops supervise and waits for it to return
+	stopSupervisor func()
 }
 
-func New(appID int, appHash string) *Client {
-	client := telegram.NewClient(
-		appID,
-		appHash,
-		telegram.Options{
-			NoUpdates: true,
-		},
-	)
+func New(appID int, appHash string, opts ...Option) *Client {
+	config := newConfig(opts)
+
+	// middlewares are called in order: requests are logged with all the retries,
+	// and every retry waits for the rate limiter
+	floodWaiter := NewFloodWaiter(config.log, config.floodRetries, config.maxFloodWait)
+	config.options.Middlewares = append(config.options.Middlewares, logger(config.log), floodWaiter)
+	if config.limiter != nil {
+		config.options.Middlewares = append(config.options.Middlewares, rateLimiter(config.limiter))
+	}
+
+	if config.options.SessionStorage == nil {
+		// the session is shared by the clients created on reconnects, so they stay authorized
+		config.options.SessionStorage = &session.StorageMemory{}
+	}
+
+	newTelegram := func() *telegram.Client {
+		return telegram.NewClient(
+			appID,
+			appHash,
+			config.options,
+		)
+	}
 
 	return &Client{
-		Client: client,
+		log:          config.log,
+		floodWaiter:  floodWaiter,
+		pingInterval: config.pingInterval,
+		pingTimeout:  config.pingTimeout,
+		states:       make(chan State, 1),
+		newTelegram:  newTelegram,
+		run: func(ctx context.Context, client *telegram.Client) (func() error, error) {
+			return bg.Connect(client, bg.WithContext(ctx))
+		},
+		pinger: func(ctx context.Context, client *telegram.Client) error {
+			return client.Ping(ctx)
+		},
+		client: newTelegram(),
 	}
 }
 
+// Telegram returns the current telegram.Client.
+// It's replaced when the client reconnects, so it must not be kept,
+// use API or the client itself as tg.Invoker to make requests
+func (c *Client) Telegram() *telegram.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.client
+}
+
+// API returns raw Telegram API making requests with the current telegram.Client
+func (c *Client) API() *tg.Client {
+	return tg.NewClient(c)
+}
+
+// FloodedUntil returns time the last FLOOD_WAIT error of the client expires at
+func (c *Client) FloodedUntil() time.Time {
+	return c.floodWaiter.FloodedUntil()
+}
+
 func (c *Client) Connect(ctx context.Context, botToken string) error {
-	stop, err := bg.Connect(c.Client)
-	if err != nil {
-		return fmt.Errorf("failed to connect telegram.Client: %w", err)
+	if err := c.connect(); err != nil {
+		return err
 	}
-	c.stop = stop
 
-	status, err := c.Client.Auth().Status(ctx)
+	status, err := c.Telegram().Auth().Status(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to auth telegram.Client: %w", err)
 	}
 
 	if !status.Authorized {
-		_, err = c.Client.Auth().Bot(ctx, botToken)
+		_, err = c.Telegram().Auth().Bot(ctx, botToken)
 		if err != nil {
 			return fmt.Errorf("failed to auth telegram bot with token %q: %w", botToken[:6], err)
 		}
@@ -53,16 +125,72 @@ func (c *Client) Connect(ctx context.Context, botToken string) error {
 	return nil
 }
 
+// connect runs the client in background along with the connection supervisor
+func (c *Client) connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stop != nil {
+		return errors.New("telegram.Client is already connected")
+	}
+
+	stop, err := c.run(context.Background(), c.client)
+	if err != nil {
+		return fmt.Errorf("failed to connect telegram.Client: %w", err)
+	}
+	c.stop = stop
+	c.setState(StateConnected)
+
+	if c.pingInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go c.supervise(ctx, done)
+
+		c.stopSupervisor = func() {
+			cancel()
+			<-done
+		}
+	}
+
+	return nil
+}
+
 func (c *Client) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
-	err := c.Client.Invoke(ctx, input, output)
+	err := c.Telegram().Invoke(ctx, input, output)
 	if err != nil {
-		return fmt.Errorf("c.Client.Invoke(ctx, input, output): %w", err)
+		return fmt.Errorf("c.Telegram().Invoke(ctx, input, output): %w", err)
 	}
 	return nil
 }
 
+// Close stops the client. It's safe to call Close if the client isn't connected
 func (c *Client) Close() error {
-	err := c.stop()
+	c.mu.Lock()
+	stopSupervisor := c.stopSupervisor
+	c.stopSupervisor = nil
+	c.mu.Unlock()
+
+	// supervisor is stopped first, so it doesn't reconnect the client
+	if stopSupervisor != nil {
+		stopSupervisor()
+	}
+
+	c.mu.Lock()
+	stop := c.stop
+	c.stop = nil
+	if stop != nil {
+		// the stopped client can't be connected again
+		c.client = c.newTelegram()
+	}
+	c.mu.Unlock()
+
+	if stop == nil {
+		return nil
+	}
+
+	c.setState(StateDisconnected)
+
+	err := stop()
 	if err != nil {
 		return fmt.Errorf("failed to stop telegram.Client: %w", err)
 	}
@@ -0,0 +1,131 @@
+package gotdclient
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"golang.org/x/time/rate"
+)
+
+// Default limits of waiting for FLOOD_WAIT errors
+const (
+	DefaultFloodRetries = 5
+	DefaultMaxFloodWait = 5 * time.Minute
+)
+
+// FloodWaiter is a middleware waiting and retrying requests failed with FLOOD_WAIT_X errors
+type FloodWaiter struct {
+	log *slog.Logger
+
+	retries int
+	maxWait time.Duration
+
+	// until is unix nanoseconds time of the end of the last flood wait
+	until atomic.Int64
+}
+
+var _ telegram.Middleware = (*FloodWaiter)(nil)
+
+// NewFloodWaiter creates middleware retrying request up to retries times.
+// Errors with wait longer than maxWait are returned without waiting
+func NewFloodWaiter(log *slog.Logger, retries int, maxWait time.Duration) *FloodWaiter {
+	return &FloodWaiter{
+		log:     log,
+		retries: retries,
+		maxWait: maxWait,
+	}
+}
+
+// FloodedUntil returns time the last FLOOD_WAIT error expires at
+func (f *FloodWaiter) FloodedUntil() time.Time {
+	return time.Unix(0, f.until.Load())
+}
+
+func (f *FloodWaiter) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		const src = "FloodWaiter.Handle"
+		log := f.log.With(
+			slog.String("src", src),
+			slog.String("request", requestName(input)),
+		)
+
+		for attempt := 0; ; attempt++ {
+			err := next.Invoke(ctx, input, output)
+
+			wait, ok := tgerr.AsFloodWait(err)
+			if !ok {
+				return err
+			}
+
+			// the limit is tracked even if the error is returned, so the client isn't used until it expires
+			f.until.Store(time.Now().Add(wait).UnixNano())
+
+			if attempt >= f.retries || wait > f.maxWait {
+				return fmt.Errorf("flood wait of %s after %d retries: %w", wait, attempt, err)
+			}
+
+			log.Warn("waiting for flood limit", slog.Duration("wait", wait), slog.Int("attempt", attempt+1))
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("flood wait of %s: %w", wait, ctx.Err())
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// rateLimiter delays requests to keep their rate under the limit
+func rateLimiter(limiter *rate.Limiter) telegram.Middleware {
+	return telegram.MiddlewareFunc(func(next tg.Invoker) telegram.InvokeFunc {
+		return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			if err := limiter.Wait(ctx); err != nil {
+				return fmt.Errorf("limiter.Wait(): %w", err)
+			}
+
+			return next.Invoke(ctx, input, output)
+		}
+	})
+}
+
+// logger logs every request with its duration and error at Debug level
+func logger(log *slog.Logger) telegram.Middleware {
+	return telegram.MiddlewareFunc(func(next tg.Invoker) telegram.InvokeFunc {
+		return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			const src = "gotdclient.logger"
+
+			start := time.Now()
+			err := next.Invoke(ctx, input, output)
+
+			attrs := []any{
+				slog.String("src", src),
+				slog.String("request", requestName(input)),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				log.Debug("request failed", append(attrs, slog.String("error", err.Error()))...)
+			} else {
+				log.Debug("request completed", attrs...)
+			}
+
+			return err
+		}
+	})
+}
+
+func requestName(input bin.Encoder) string {
+	if named, ok := input.(interface{ TypeName() string }); ok {
+		return named.TypeName()
+	}
+
+	return fmt.Sprintf("%T", input)
+}
@@ -0,0 +1,77 @@
+package gotdclient_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tgerr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/gotdclient"
+)
+
+func TestFloodWaiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		retries   int
+		floodWait string
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "no_flood",
+			retries:   3,
+			floodWait: "FLOOD_WAIT_0",
+			wantCalls: 1,
+		}, {
+			name:      "retried_until_success",
+			failures:  2,
+			retries:   3,
+			floodWait: "FLOOD_WAIT_0",
+			wantCalls: 3,
+		}, {
+			name:      "retries_exceeded",
+			failures:  5,
+			retries:   2,
+			floodWait: "FLOOD_WAIT_0",
+			wantCalls: 3,
+			wantErr:   true,
+		}, {
+			name:      "wait_too_long",
+			failures:  1,
+			retries:   3,
+			floodWait: "FLOOD_WAIT_3600",
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			invoker := telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+				calls++
+				if calls <= test.failures {
+					return tgerr.New(420, test.floodWait)
+				}
+				return nil
+			})
+
+			waiter := gotdclient.NewFloodWaiter(slog.Default(), test.retries, time.Minute)
+			err := waiter.Handle(invoker).Invoke(context.Background(), nil, nil)
+
+			require.Equal(t, test.wantCalls, calls)
+			if test.wantErr {
+				require.Error(t, err)
+				require.True(t, tgerr.Is(err, "FLOOD_WAIT"))
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package gotdclient
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram"
+	"golang.org/x/time/rate"
+)
+
+// Option configures Client created by New
+type Option func(c *config)
+
+type config struct {
+	options telegram.Options
+
+	log *slog.Logger
+
+	floodRetries int
+	maxFloodWait time.Duration
+
+	limiter *rate.Limiter
+
+	pingInterval time.Duration
+	pingTimeout  time.Duration
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		options: telegram.Options{
+			NoUpdates: true,
+		},
+		log:          slog.Default(),
+		floodRetries: DefaultFloodRetries,
+		maxFloodWait: DefaultMaxFloodWait,
+		pingInterval: DefaultPingInterval,
+		pingTimeout:  DefaultPingTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// WithSessionStorage makes client keep its MTProto session in the storage,
+// so it's authorized again after restart without a new login.
+// Without a storage every start performs a fresh login
+func WithSessionStorage(storage telegram.SessionStorage) Option {
+	return func(c *config) {
+		c.options.SessionStorage = storage
+	}
+}
+
+// WithSessionFile makes client keep its MTProto session in the file located on the path
+func WithSessionFile(path string) Option {
+	return WithSessionStorage(&session.FileStorage{Path: path})
+}
+
+// WithLogger sets logger of the client requests and flood waits
+func WithLogger(log *slog.Logger) Option {
+	return func(c *config) {
+		c.log = log
+	}
+}
+
+// WithFloodWait makes client retry requests failed with FLOOD_WAIT_X errors up to retries times
+// if the wait is not longer than maxWait. Zero retries disables waiting.
+// DefaultFloodRetries and DefaultMaxFloodWait are used by default
+func WithFloodWait(retries int, maxWait time.Duration) Option {
+	return func(c *config) {
+		c.floodRetries = retries
+		c.maxFloodWait = maxWait
+	}
+}
+
+// WithRateLimit limits rate of the client requests to limit per second with bursts of burst requests
+func WithRateLimit(limit rate.Limit, burst int) Option {
+	return func(c *config) {
+		c.limiter = rate.NewLimiter(limit, burst)
+	}
+}
+
+// WithPing makes client ping the server every interval and reconnect if there is no answer in timeout.
+// Zero interval disables the connection supervisor.
+// DefaultPingInterval and DefaultPingTimeout are used by default
+func WithPing(interval time.Duration, timeout time.Duration) Option {
+	return func(c *config) {
+		c.pingInterval = interval
+		c.pingTimeout = timeout
+	}
+}
+
+// WithUpdateHandler makes client receive updates and pass them to the handler, e.g. tg.UpdateDispatcher.
+// Updates are not received by default
+func WithUpdateHandler(handler telegram.UpdateHandler) Option {
+	return func(c *config) {
+		c.options.NoUpdates = false
+		c.options.UpdateHandler = handler
+	}
+}
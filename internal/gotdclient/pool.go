@@ -0,0 +1,155 @@
+package gotdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Strategy is a way Pool selects a client
+type Strategy int
+
+const (
+	// RoundRobin selects clients one by one
+	RoundRobin Strategy = iota
+	// LeastLoaded selects a client with the least number of acquired requests
+	LeastLoaded
+)
+
+// ErrNoHealthyClients is returned by Pool.Acquire if none of the clients is connected
+var ErrNoHealthyClients = errors.New("no healthy clients")
+
+// PoolClient is a client selected by Pool. It's implemented by Client
+type PoolClient interface {
+	// Healthy reports whether the client is connected
+	Healthy() bool
+	// FloodedUntil returns time the last FLOOD_WAIT error of the client expires at
+	FloodedUntil() time.Time
+	Close() error
+}
+
+var _ PoolClient = (*Client)(nil)
+
+// Pool distributes load over several connected clients, e.g. bots with different tokens.
+// Clients which are not healthy or wait for FLOOD_WAIT expiration are skipped
+type Pool struct {
+	clients  []PoolClient
+	strategy Strategy
+
+	mu   sync.Mutex
+	next int
+	// load is a number of acquired requests per client
+	load []int
+}
+
+func NewPool(clients []PoolClient, strategy Strategy) (*Pool, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("pool must contain at least one client")
+	}
+
+	return &Pool{
+		clients:  clients,
+		strategy: strategy,
+		load:     make([]int, len(clients)),
+	}, nil
+}
+
+// Clients returns clients of the pool, Acquire returns indexes of this slice
+func (p *Pool) Clients() []PoolClient {
+	return p.clients
+}
+
+// Acquire selects a client and returns its index in Clients.
+// release must be called after the client is no longer used.
+//
+// If all healthy clients are flooded, Acquire waits for the earliest flood wait expiration
+func (p *Pool) Acquire(ctx context.Context) (index int, release func(), err error) {
+	for {
+		index, wait, err := p.acquire()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if index >= 0 {
+			var once sync.Once
+			return index, func() {
+				once.Do(func() {
+					p.mu.Lock()
+					p.load[index]--
+					p.mu.Unlock()
+				})
+			}, nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, nil, fmt.Errorf("all clients are flooded: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// acquire returns index of the selected client or -1 and time to wait for a flooded client
+func (p *Pool) acquire() (int, time.Duration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	selected, healthy := -1, false
+	var wait time.Duration
+
+	for i := range p.clients {
+		// round robin starts from the client next to the previously selected one
+		index := (p.next + i) % len(p.clients)
+		client := p.clients[index]
+
+		if !client.Healthy() {
+			continue
+		}
+		healthy = true
+
+		if flooded := client.FloodedUntil().Sub(now); flooded > 0 {
+			if wait == 0 || flooded < wait {
+				wait = flooded
+			}
+			continue
+		}
+
+		if selected < 0 {
+			selected = index
+			if p.strategy == RoundRobin {
+				break
+			}
+		} else if p.load[index] < p.load[selected] {
+			selected = index
+		}
+	}
+
+	if !healthy {
+		return -1, 0, ErrNoHealthyClients
+	}
+	if selected < 0 {
+		return -1, wait, nil
+	}
+
+	p.load[selected]++
+	p.next = selected + 1
+
+	return selected, 0, nil
+}
+
+// Close closes all the clients
+func (p *Pool) Close() error {
+	var errs []error
+	for i, client := range p.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("client %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,120 @@
+package gotdclient_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/gotdclient"
+)
+
+type poolClient struct {
+	healthy      bool
+	floodedUntil time.Time
+}
+
+func (c *poolClient) Healthy() bool {
+	return c.healthy
+}
+
+func (c *poolClient) FloodedUntil() time.Time {
+	return c.floodedUntil
+}
+
+func (c *poolClient) Close() error {
+	return nil
+}
+
+func newPool(t *testing.T, strategy gotdclient.Strategy, clients ...*poolClient) *gotdclient.Pool {
+	poolClients := make([]gotdclient.PoolClient, len(clients))
+	for i, client := range clients {
+		poolClients[i] = client
+	}
+
+	pool, err := gotdclient.NewPool(poolClients, strategy)
+	require.NoError(t, err)
+
+	return pool
+}
+
+func acquire(t *testing.T, pool *gotdclient.Pool) (int, func()) {
+	index, release, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+
+	return index, release
+}
+
+func TestPool_RoundRobin(t *testing.T) {
+	pool := newPool(t, gotdclient.RoundRobin,
+		&poolClient{healthy: true},
+		&poolClient{healthy: false},
+		&poolClient{healthy: true},
+		&poolClient{healthy: true, floodedUntil: time.Now().Add(time.Hour)},
+	)
+
+	var selected []int
+	for i := 0; i < 4; i++ {
+		index, release := acquire(t, pool)
+		release()
+		selected = append(selected, index)
+	}
+
+	// unhealthy and flooded clients are skipped
+	require.Equal(t, []int{0, 2, 0, 2}, selected)
+}
+
+func TestPool_LeastLoaded(t *testing.T) {
+	pool := newPool(t, gotdclient.LeastLoaded,
+		&poolClient{healthy: true},
+		&poolClient{healthy: true},
+		&poolClient{healthy: true},
+	)
+
+	first, releaseFirst := acquire(t, pool)
+	second, releaseSecond := acquire(t, pool)
+	third, releaseThird := acquire(t, pool)
+	require.ElementsMatch(t, []int{0, 1, 2}, []int{first, second, third})
+
+	releaseSecond()
+	// release is idempotent
+	releaseSecond()
+	index, release := acquire(t, pool)
+	require.Equal(t, second, index)
+
+	release()
+	releaseFirst()
+	releaseThird()
+}
+
+func TestPool_Flooded(t *testing.T) {
+	pool := newPool(t, gotdclient.RoundRobin,
+		&poolClient{healthy: true, floodedUntil: time.Now().Add(time.Hour)},
+		&poolClient{healthy: true, floodedUntil: time.Now().Add(50 * time.Millisecond)},
+	)
+
+	// the earliest flood wait expiration is awaited
+	start := time.Now()
+	index, release := acquire(t, pool)
+	release()
+	require.Equal(t, 1, index)
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	pool = newPool(t, gotdclient.RoundRobin, &poolClient{healthy: true, floodedUntil: time.Now().Add(time.Hour)})
+	_, _, err := pool.Acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPool_NoHealthyClients(t *testing.T) {
+	pool := newPool(t, gotdclient.LeastLoaded, &poolClient{}, &poolClient{})
+
+	_, _, err := pool.Acquire(context.Background())
+	require.ErrorIs(t, err, gotdclient.ErrNoHealthyClients)
+
+	_, err = gotdclient.NewPool(nil, gotdclient.RoundRobin)
+	require.Error(t, err)
+}
@@ -0,0 +1,329 @@
+package gotdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/peer"
+	"github.com/gotd/td/telegram/query"
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+// channelIDOffset is added to channel and supergroup IDs by Bot API, e.g. -1002184825487
+const channelIDOffset = 1_000_000_000_000
+
+// ErrNotMember is returned if the invite link refers to a chat the client isn't a member of
+var ErrNotMember = errors.New("not a member of the chat")
+
+// Resolver resolves targets given as numeric Bot API IDs (-1002184825487),
+// @usernames, t.me links and invite links (t.me/+hash, t.me/joinchat/hash).
+//
+// Resolved peers are cached by the target, so repeated sends don't call Telegram again.
+// It implements uploader.Resolver
+type Resolver struct {
+	api    *tg.Client
+	sender *message.Sender
+	next   peer.Resolver
+
+	mu    sync.RWMutex
+	peers map[string]tg.InputPeerClass
+	// hashes holds access hashes of users and channels by their Bot API IDs
+	hashes map[int64]int64
+
+	// dialogs are loaded once to fill hashes of the peers the account talked to
+	dialogs sync.Once
+}
+
+func NewResolver(api *tg.Client, sender *message.Sender) *Resolver {
+	return &Resolver{
+		api:    api,
+		sender: sender,
+		next:   peer.SingleflightResolver(peer.Plain(api)),
+		peers:  make(map[string]tg.InputPeerClass),
+		hashes: make(map[int64]int64),
+	}
+}
+
+// Resolve creates a message builder sending to the peer of from
+func (r *Resolver) Resolve(from string, decorators ...peer.PromiseDecorator) *message.RequestBuilder {
+	return r.sender.PeerPromise(func(ctx context.Context) (tg.InputPeerClass, error) {
+		return r.ResolvePeer(ctx, from)
+	}, decorators...)
+}
+
+// ResolvePeer returns input peer of from, see Resolver for supported formats
+func (r *Resolver) ResolvePeer(ctx context.Context, from string) (tg.InputPeerClass, error) {
+	key := cacheKey(from)
+
+	r.mu.RLock()
+	inputPeer, ok := r.peers[key]
+	r.mu.RUnlock()
+	if ok {
+		return inputPeer, nil
+	}
+
+	inputPeer, err := r.resolve(ctx, strings.TrimSpace(from))
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.peers[key] = inputPeer
+	r.rememberHash(inputPeer)
+	r.mu.Unlock()
+
+	return inputPeer, nil
+}
+
+// Remember caches the peer of target, e.g. one received in updates,
+// so it's resolved without requests to Telegram
+func (r *Resolver) Remember(target string, inputPeer tg.InputPeerClass) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.peers[cacheKey(target)] = inputPeer
+	r.rememberHash(inputPeer)
+}
+
+// RememberEntities stores access hashes of the users and channels, e.g. ones received in updates,
+// so they can be resolved by numeric IDs. User accounts can't use numeric IDs without access hashes
+func (r *Resolver) RememberEntities(users map[int64]*tg.User, channels map[int64]*tg.Channel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range users {
+		// min constructors have access hashes valid only with the message they came with
+		if !user.Min {
+			r.rememberHash(user.AsInputPeer())
+		}
+	}
+
+	for _, channel := range channels {
+		if !channel.Min {
+			r.rememberHash(channel.AsInputPeer())
+		}
+	}
+}
+
+// rememberHash stores access hash of the user or channel peer. r.mu must be locked
+func (r *Resolver) rememberHash(inputPeer tg.InputPeerClass) {
+	switch p := inputPeer.(type) {
+	case *tg.InputPeerUser:
+		if p.AccessHash != 0 {
+			r.hashes[p.UserID] = p.AccessHash
+		}
+	case *tg.InputPeerChannel:
+		if p.AccessHash != 0 {
+			r.hashes[-channelIDOffset-p.ChannelID] = p.AccessHash
+		}
+	}
+}
+
+// accessHash returns access hash of the user or channel with Bot API ID.
+// Dialogs of the account are loaded on the first miss
+func (r *Resolver) accessHash(ctx context.Context, id int64) (int64, bool) {
+	r.mu.RLock()
+	hash, ok := r.hashes[id]
+	r.mu.RUnlock()
+	if ok {
+		return hash, true
+	}
+
+	r.dialogs.Do(func() {
+		// bots can't get dialogs, they use zero access hashes
+		_ = r.loadDialogs(ctx)
+	})
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hash, ok = r.hashes[id]
+	return hash, ok
+}
+
+func (r *Resolver) loadDialogs(ctx context.Context) error {
+	return query.GetDialogs(r.api).BatchSize(100).ForEach(ctx, func(_ context.Context, elem dialogs.Elem) error {
+		r.RememberEntities(elem.Entities.Users(), elem.Entities.Channels())
+		return nil
+	})
+}
+
+// BotAPIID returns Bot API ID of the peer: users have positive IDs,
+// basic groups have negative IDs and channels have IDs below -10^12
+func BotAPIID(p tg.PeerClass) int64 {
+	switch p := p.(type) {
+	case *tg.PeerUser:
+		return p.UserID
+	case *tg.PeerChat:
+		return -p.ChatID
+	case *tg.PeerChannel:
+		return -channelIDOffset - p.ChannelID
+	default:
+		return 0
+	}
+}
+
+// Forget removes cached peer of from, e.g. after the peer became invalid
+func (r *Resolver) Forget(from string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.peers, cacheKey(from))
+}
+
+func (r *Resolver) resolve(ctx context.Context, from string) (tg.InputPeerClass, error) {
+	if id, err := strconv.ParseInt(from, 10, 64); err == nil {
+		inputPeer, err := r.resolveID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("r.resolveID(ctx, %d): %w", id, err)
+		}
+		return inputPeer, nil
+	}
+
+	if hash, ok := inviteHash(from); ok {
+		inputPeer, err := r.resolveInvite(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("r.resolveInvite(ctx, %q): %w", hash, err)
+		}
+		return inputPeer, nil
+	}
+
+	inputPeer, err := peer.Resolve(r.next, from)(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("peer.Resolve(%q): %w", from, err)
+	}
+
+	return inputPeer, nil
+}
+
+// resolveID resolves Bot API ID: negative IDs below -10^12 are channels,
+// other negative IDs are basic groups and positive IDs are users.
+//
+// Users and channels are looked up among the known access hashes first,
+// otherwise they are requested with zero access hash which is accepted only from bots
+func (r *Resolver) resolveID(ctx context.Context, id int64) (tg.InputPeerClass, error) {
+	switch {
+	case id <= -channelIDOffset:
+		channelID := -id - channelIDOffset
+		if hash, ok := r.accessHash(ctx, id); ok {
+			return &tg.InputPeerChannel{ChannelID: channelID, AccessHash: hash}, nil
+		}
+
+		chats, err := r.api.ChannelsGetChannels(ctx, []tg.InputChannelClass{
+			&tg.InputChannel{ChannelID: channelID},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("r.api.ChannelsGetChannels(ctx, %d): %w", channelID, err)
+		}
+
+		for _, chat := range chats.GetChats() {
+			if channel, ok := chat.(*tg.Channel); ok && channel.ID == channelID {
+				return channel.AsInputPeer(), nil
+			}
+		}
+
+		return nil, fmt.Errorf("channel %d not found", channelID)
+	case id < 0:
+		return &tg.InputPeerChat{ChatID: -id}, nil
+	case id > 0:
+		if hash, ok := r.accessHash(ctx, id); ok {
+			return &tg.InputPeerUser{UserID: id, AccessHash: hash}, nil
+		}
+
+		users, err := r.api.UsersGetUsers(ctx, []tg.InputUserClass{
+			&tg.InputUser{UserID: id},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("r.api.UsersGetUsers(ctx, %d): %w", id, err)
+		}
+
+		for _, user := range users {
+			if user, ok := user.(*tg.User); ok && user.ID == id {
+				return user.AsInputPeer(), nil
+			}
+		}
+
+		return nil, fmt.Errorf("user %d not found", id)
+	default:
+		return nil, errors.New("zero peer ID")
+	}
+}
+
+func (r *Resolver) resolveInvite(ctx context.Context, hash string) (tg.InputPeerClass, error) {
+	invite, err := r.api.MessagesCheckChatInvite(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("r.api.MessagesCheckChatInvite(ctx, %q): %w", hash, err)
+	}
+
+	var chat tg.ChatClass
+	switch invite := invite.(type) {
+	case *tg.ChatInviteAlready:
+		chat = invite.Chat
+	case *tg.ChatInvitePeek:
+		chat = invite.Chat
+	default:
+		return nil, ErrNotMember
+	}
+
+	switch chat := chat.(type) {
+	case *tg.Channel:
+		return chat.AsInputPeer(), nil
+	case *tg.Chat:
+		return chat.AsInputPeer(), nil
+	default:
+		return nil, fmt.Errorf("unexpected chat %T", chat)
+	}
+}
+
+// inviteHash returns hash of the invite link like t.me/+hash, t.me/joinchat/hash or tg://join?invite=hash
+func inviteHash(from string) (string, bool) {
+	if !strings.Contains(from, "://") {
+		from = "https://" + from
+	}
+
+	link, err := url.Parse(from)
+	if err != nil {
+		return "", false
+	}
+
+	if link.Scheme == "tg" {
+		hash := link.Query().Get("invite")
+		return hash, link.Host == "join" && hash != ""
+	}
+
+	switch strings.ToLower(link.Host) {
+	case "t.me", "telegram.me", "telegram.dog":
+	default:
+		return "", false
+	}
+
+	path := strings.Trim(link.Path, "/")
+	if hash, ok := strings.CutPrefix(path, "+"); ok && hash != "" {
+		// t.me/+<digits> is a link to the phone number
+		_, err := strconv.ParseUint(hash, 10, 64)
+		return hash, err != nil
+	}
+	if hash, ok := strings.CutPrefix(path, "joinchat/"); ok && hash != "" {
+		return hash, true
+	}
+
+	return "", false
+}
+
+// cacheKey ignores the case of usernames and links, but keeps it for invite links
+// since invite hashes are case-sensitive
+func cacheKey(from string) string {
+	from = strings.TrimSpace(from)
+	if _, ok := inviteHash(from); ok {
+		return from
+	}
+
+	return strings.ToLower(strings.TrimPrefix(from, "@"))
+}
@@ -0,0 +1,120 @@
+package gotdclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteHash(t *testing.T) {
+	tests := []struct {
+		from string
+		hash string
+		ok   bool
+	}{
+		{from: "https://t.me/+AbCdEf123", hash: "AbCdEf123", ok: true},
+		{from: "t.me/joinchat/AbCdEf123", hash: "AbCdEf123", ok: true},
+		{from: "telegram.me/+AbCdEf123/", hash: "AbCdEf123", ok: true},
+		{from: "tg://join?invite=AbCdEf123", hash: "AbCdEf123", ok: true},
+		{from: "https://t.me/+79991234567", ok: false},
+		{from: "https://t.me/my_channel", ok: false},
+		{from: "https://example.com/+AbCdEf123", ok: false},
+		{from: "@my_channel", ok: false},
+		{from: "-1002184825487", ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.from, func(t *testing.T) {
+			hash, ok := inviteHash(test.from)
+			require.Equal(t, test.ok, ok)
+			if test.ok {
+				require.Equal(t, test.hash, hash)
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	tests := []struct {
+		from string
+		key  string
+	}{
+		{from: "@My_Channel", key: "my_channel"},
+		{from: " my_channel ", key: "my_channel"},
+		{from: "https://T.me/My_Channel", key: "https://t.me/my_channel"},
+		{from: "https://t.me/+AbCdEf123", key: "https://t.me/+AbCdEf123"},
+		{from: "-1002184825487", key: "-1002184825487"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.from, func(t *testing.T) {
+			require.Equal(t, test.key, cacheKey(test.from))
+		})
+	}
+}
+
+func TestBotAPIID(t *testing.T) {
+	tests := []struct {
+		name string
+		peer tg.PeerClass
+		id   int64
+	}{
+		{name: "user", peer: &tg.PeerUser{UserID: 42}, id: 42},
+		{name: "chat", peer: &tg.PeerChat{ChatID: 42}, id: -42},
+		{name: "channel", peer: &tg.PeerChannel{ChannelID: 2184825487}, id: -1002184825487},
+		{name: "nil", peer: nil, id: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.id, BotAPIID(test.peer))
+		})
+	}
+}
+
+type failingInvoker struct {
+	calls *int
+}
+
+func (i failingInvoker) Invoke(_ context.Context, _ bin.Encoder, _ bin.Decoder) error {
+	*i.calls++
+	return errors.New("BOT_METHOD_INVALID")
+}
+
+func TestResolver_ResolvePeer(t *testing.T) {
+	var calls int
+	r := NewResolver(tg.NewClient(failingInvoker{calls: &calls}), nil)
+
+	user := &tg.User{ID: 42}
+	user.SetAccessHash(4242)
+	channel := &tg.Channel{ID: 2184825487}
+	channel.SetAccessHash(8484)
+
+	r.RememberEntities(map[int64]*tg.User{user.ID: user}, map[int64]*tg.Channel{channel.ID: channel})
+
+	channelPeer, err := r.ResolvePeer(context.Background(), "-1002184825487")
+	require.NoError(t, err)
+	require.Equal(t, &tg.InputPeerChannel{ChannelID: 2184825487, AccessHash: 8484}, channelPeer)
+
+	userPeer, err := r.ResolvePeer(context.Background(), "42")
+	require.NoError(t, err)
+	require.Equal(t, &tg.InputPeerUser{UserID: 42, AccessHash: 4242}, userPeer)
+
+	require.Zero(t, calls)
+
+	// dialogs are loaded once, then the user is requested with zero access hash
+	_, err = r.ResolvePeer(context.Background(), "43")
+	require.Error(t, err)
+	_, err = r.ResolvePeer(context.Background(), "44")
+	require.Error(t, err)
+	require.Equal(t, 3, calls)
+
+	r.Remember("@My_Channel", channelPeer)
+	cached, err := r.ResolvePeer(context.Background(), "my_channel")
+	require.NoError(t, err)
+	require.Equal(t, channelPeer, cached)
+}
@@ -0,0 +1,124 @@
+package gotdclient
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram"
+)
+
+// SessionStore stores MTProto sessions by name.
+// It's implemented by backend.Database
+type SessionStore interface {
+	// GetTelegramSession returns sql.ErrNoRows if there is no session named name
+	GetTelegramSession(ctx context.Context, name string) ([]byte, error)
+	SaveTelegramSession(ctx context.Context, name string, data []byte) error
+}
+
+// DatabaseStorage is a session storage keeping a session named name in the SessionStore
+type DatabaseStorage struct {
+	store SessionStore
+	name  string
+}
+
+var _ telegram.SessionStorage = (*DatabaseStorage)(nil)
+
+func NewDatabaseStorage(store SessionStore, name string) *DatabaseStorage {
+	return &DatabaseStorage{
+		store: store,
+		name:  name,
+	}
+}
+
+func (s *DatabaseStorage) LoadSession(ctx context.Context) ([]byte, error) {
+	data, err := s.store.GetTelegramSession(ctx, s.name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, session.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("s.store.GetTelegramSession(ctx, %q): %w", s.name, err)
+	}
+
+	return data, nil
+}
+
+func (s *DatabaseStorage) StoreSession(ctx context.Context, data []byte) error {
+	if err := s.store.SaveTelegramSession(ctx, s.name, data); err != nil {
+		return fmt.Errorf("s.store.SaveTelegramSession(ctx, %q): %w", s.name, err)
+	}
+
+	return nil
+}
+
+// EncryptedStorage encrypts sessions with AES-256-GCM before passing them to the next storage,
+// so a leaked file or database dump doesn't give access to the account
+type EncryptedStorage struct {
+	next telegram.SessionStorage
+	aead cipher.AEAD
+}
+
+var _ telegram.SessionStorage = (*EncryptedStorage)(nil)
+
+// NewEncryptedStorage wraps the next storage. Encryption key is derived from secret of any length
+func NewEncryptedStorage(next telegram.SessionStorage, secret []byte) (*EncryptedStorage, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("empty session secret")
+	}
+
+	key := sha256.Sum256(secret)
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher(): %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cipher.NewGCM(): %w", err)
+	}
+
+	return &EncryptedStorage{
+		next: next,
+		aead: aead,
+	}, nil
+}
+
+func (s *EncryptedStorage) LoadSession(ctx context.Context) ([]byte, error) {
+	data, err := s.next.LoadSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("s.next.LoadSession(): %w", err)
+	}
+
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("encrypted session of %d bytes is too short", len(data))
+	}
+
+	plain, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt session: %w", err)
+	}
+
+	return plain, nil
+}
+
+func (s *EncryptedStorage) StoreSession(ctx context.Context, data []byte) error {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("rand.Read(): %w", err)
+	}
+
+	// nonce is stored before the ciphertext
+	if err := s.next.StoreSession(ctx, s.aead.Seal(nonce, nonce, data, nil)); err != nil {
+		return fmt.Errorf("s.next.StoreSession(): %w", err)
+	}
+
+	return nil
+}
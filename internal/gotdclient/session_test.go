@@ -0,0 +1,53 @@
+package gotdclient_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/gotd/td/session"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/gotdclient"
+)
+
+type memoryStore map[string][]byte
+
+func (s memoryStore) GetTelegramSession(_ context.Context, name string) ([]byte, error) {
+	data, ok := s[name]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return data, nil
+}
+
+func (s memoryStore) SaveTelegramSession(_ context.Context, name string, data []byte) error {
+	s[name] = data
+	return nil
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(`{"Version":1,"Data":{"DC":2}}`)
+
+	store := memoryStore{}
+	storage, err := gotdclient.NewEncryptedStorage(gotdclient.NewDatabaseStorage(store, "bot"), []byte("secret"))
+	require.NoError(t, err)
+
+	_, err = storage.LoadSession(ctx)
+	require.ErrorIs(t, err, session.ErrNotFound)
+
+	require.NoError(t, storage.StoreSession(ctx, data))
+	require.NotContains(t, string(store["bot"]), "Version")
+
+	loaded, err := storage.LoadSession(ctx)
+	require.NoError(t, err)
+	require.Equal(t, data, loaded)
+
+	wrongKey, err := gotdclient.NewEncryptedStorage(gotdclient.NewDatabaseStorage(store, "bot"), []byte("other"))
+	require.NoError(t, err)
+
+	_, err = wrongKey.LoadSession(ctx)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, session.ErrNotFound)
+}
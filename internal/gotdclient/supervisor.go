@@ -0,0 +1,171 @@
+package gotdclient
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// State is a state of the client connection
+type State int
+
+const (
+	StateDisconnected State = iota
+	StateConnected
+	StateReconnecting
+)
+
+func (s State) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// Default settings of the connection supervisor
+const (
+	DefaultPingInterval = time.Minute
+	DefaultPingTimeout  = 15 * time.Second
+
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// Healthy reports whether the client is connected and answered the last ping
+func (c *Client) Healthy() bool {
+	return c.State() == StateConnected
+}
+
+// State returns the current state of the client connection
+func (c *Client) State() State {
+	return State(c.state.Load())
+}
+
+// States returns channel of the connection state changes.
+// Changes are dropped if the channel isn't read in time
+func (c *Client) States() <-chan State {
+	return c.states
+}
+
+func (c *Client) setState(state State) {
+	if State(c.state.Swap(int64(state))) == state {
+		return
+	}
+
+	c.log.Info("connection state changed",
+		slog.String("src", "Client.setState"),
+		slog.String("state", state.String()),
+	)
+
+	select {
+	case c.states <- state:
+	default:
+	}
+}
+
+// supervise pings the server every ping interval and reconnects the client if the ping fails
+func (c *Client) supervise(ctx context.Context, done chan<- struct{}) {
+	const src = "Client.supervise"
+	log := c.log.With(slog.String("src", src))
+
+	defer close(done)
+
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.ping(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Warn("ping failed, reconnecting", slog.String("error", err.Error()))
+			c.reconnect(ctx)
+			continue
+		}
+
+		c.setState(StateConnected)
+	}
+}
+
+func (c *Client) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.pingTimeout)
+	defer cancel()
+
+	if err := c.pinger(ctx, c.Telegram()); err != nil {
+		return fmt.Errorf("c.pinger(ctx): %w", err)
+	}
+
+	return nil
+}
+
+// reconnect restarts the client with exponential backoff until it's connected and answers a ping
+// or ctx is done. The session storage is shared, so the client stays authorized
+func (c *Client) reconnect(ctx context.Context) {
+	const src = "Client.reconnect"
+	log := c.log.With(slog.String("src", src))
+
+	c.setState(StateReconnecting)
+
+	for delay := minReconnectDelay; ; delay = min(delay*2, maxReconnectDelay) {
+		c.mu.Lock()
+		stop := c.stop
+		c.stop = nil
+		c.mu.Unlock()
+
+		// stop waits for the client to shut down, so it's called without the lock.
+		// The error of the dead connection is expected
+		if stop != nil {
+			_ = stop()
+		}
+
+		err := c.restart(ctx)
+		if err == nil {
+			err = c.ping(ctx)
+		}
+		if err == nil {
+			c.setState(StateConnected)
+			return
+		}
+
+		log.Warn("failed to reconnect", slog.String("error", err.Error()), slog.Duration("retry_in", delay))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// restart runs a new telegram.Client in background and replaces the stopped one with it
+func (c *Client) restart(ctx context.Context) error {
+	client := c.newTelegram()
+
+	stop, err := c.run(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to connect telegram.Client: %w", err)
+	}
+
+	c.mu.Lock()
+	c.client = client
+	c.stop = stop
+	c.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package gotdclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/telegram"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConnector runs no connections, it records the clients it's asked to run
+type fakeConnector struct {
+	mu      sync.Mutex
+	clients []*telegram.Client
+	// failures is a number of the runs to fail after the first one
+	failures int
+	stops    int
+	// teardown is called by stop like the shutdown of a real connection
+	teardown func()
+
+	down atomic.Bool
+}
+
+func (f *fakeConnector) run(_ context.Context, client *telegram.Client) (func() error, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.clients = append(f.clients, client)
+	if len(f.clients) > 1 && f.failures > 0 {
+		f.failures--
+		return nil, errors.New("dial failed")
+	}
+
+	return func() error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.stops++
+		if f.teardown != nil {
+			f.teardown()
+		}
+		return nil
+	}, nil
+}
+
+func (f *fakeConnector) ping(_ context.Context, _ *telegram.Client) error {
+	if f.down.Load() {
+		return errors.New("connection is dead")
+	}
+	return nil
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	c := New(1, "hash",
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithPing(10*time.Millisecond, time.Second),
+	)
+
+	connector := &fakeConnector{failures: 1}
+	// the client is usable while the dead connection shuts down
+	connector.teardown = func() { _ = c.Telegram() }
+	c.run = connector.run
+	c.pinger = connector.ping
+
+	first := c.Telegram()
+	require.NoError(t, c.connect())
+	require.True(t, c.Healthy())
+
+	connector.down.Store(true)
+	require.Eventually(t, func() bool {
+		return c.State() == StateReconnecting
+	}, time.Second, time.Millisecond)
+
+	connector.down.Store(false)
+	require.Eventually(t, c.Healthy, 5*time.Second, 10*time.Millisecond)
+
+	connector.mu.Lock()
+	// the first run, the failed one and the successful one
+	require.Len(t, connector.clients, 3)
+	// stopped telegram.Client can't run again, every reconnect runs a new one
+	require.NotSame(t, connector.clients[0], connector.clients[1])
+	require.NotSame(t, connector.clients[1], connector.clients[2])
+	require.Same(t, connector.clients[2], c.Telegram())
+	require.Equal(t, 1, connector.stops)
+	connector.mu.Unlock()
+
+	require.NotSame(t, first, c.Telegram())
+
+	require.NoError(t, c.Close())
+	require.Equal(t, StateDisconnected, c.State())
+	require.NoError(t, c.Close())
+}
+
+func TestClient_CloseNotConnected(t *testing.T) {
+	c := New(1, "hash")
+	require.NoError(t, c.Close())
+}
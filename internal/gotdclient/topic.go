@@ -0,0 +1,118 @@
+package gotdclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/gotd/td/tg"
+)
+
+// maxTopicTitleSize is the maximum UTF-8 length of a forum topic title
+const maxTopicTitleSize = 128
+
+// CreateTopic creates topic titled title in the forum supergroup target and returns its ID,
+// messages replying to the topic ID are posted inside the topic.
+// 0 is returned without an error if the target isn't a forum supergroup.
+//
+// It implements uploader.TopicCreator
+func (r *Resolver) CreateTopic(ctx context.Context, target string, title string) (int, error) {
+	inputPeer, err := r.ResolvePeer(ctx, target)
+	if err != nil {
+		return 0, fmt.Errorf("r.ResolvePeer(ctx, %q): %w", target, err)
+	}
+
+	inputChannel, ok := inputPeer.(*tg.InputPeerChannel)
+	if !ok {
+		return 0, nil
+	}
+
+	channel := &tg.InputChannel{
+		ChannelID:  inputChannel.ChannelID,
+		AccessHash: inputChannel.AccessHash,
+	}
+
+	forum, err := r.isForum(ctx, channel)
+	if err != nil {
+		return 0, fmt.Errorf("r.isForum(ctx, %d): %w", channel.ChannelID, err)
+	}
+	if !forum {
+		return 0, nil
+	}
+
+	var randomID [8]byte
+	if _, err := rand.Read(randomID[:]); err != nil {
+		return 0, fmt.Errorf("rand.Read(): %w", err)
+	}
+
+	updates, err := r.api.ChannelsCreateForumTopic(ctx, &tg.ChannelsCreateForumTopicRequest{
+		Channel:  channel,
+		Title:    truncateTitle(title),
+		RandomID: int64(binary.LittleEndian.Uint64(randomID[:])),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("r.api.ChannelsCreateForumTopic(ctx, %q): %w", title, err)
+	}
+
+	topicID, ok := createdTopicID(updates)
+	if !ok {
+		return 0, fmt.Errorf("no topic in updates %T", updates)
+	}
+
+	return topicID, nil
+}
+
+// isForum reports whether topics are enabled in the supergroup
+func (r *Resolver) isForum(ctx context.Context, channel *tg.InputChannel) (bool, error) {
+	chats, err := r.api.ChannelsGetChannels(ctx, []tg.InputChannelClass{channel})
+	if err != nil {
+		return false, fmt.Errorf("r.api.ChannelsGetChannels(ctx, %d): %w", channel.ChannelID, err)
+	}
+
+	for _, chat := range chats.GetChats() {
+		if found, ok := chat.(*tg.Channel); ok && found.ID == channel.ChannelID {
+			return found.Forum, nil
+		}
+	}
+
+	return false, fmt.Errorf("channel %d not found", channel.ChannelID)
+}
+
+// createdTopicID returns ID of the service message created the topic, it's the topic ID
+func createdTopicID(updates tg.UpdatesClass) (int, bool) {
+	list, ok := updates.(*tg.Updates)
+	if !ok {
+		return 0, false
+	}
+
+	for _, update := range list.Updates {
+		update, ok := update.(*tg.UpdateNewChannelMessage)
+		if !ok {
+			continue
+		}
+
+		if msg, ok := update.Message.(*tg.MessageService); ok {
+			if _, ok := msg.Action.(*tg.MessageActionTopicCreate); ok {
+				return msg.ID, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// truncateTitle cuts title to the maximum size without breaking UTF-8 characters
+func truncateTitle(title string) string {
+	if len(title) <= maxTopicTitleSize {
+		return title
+	}
+
+	cut := maxTopicTitleSize
+	for cut > 0 && !utf8.RuneStart(title[cut]) {
+		cut--
+	}
+
+	return title[:cut]
+}
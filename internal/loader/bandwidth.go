@@ -0,0 +1,263 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	g "github.com/anacrolix/generics"
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+	"golang.org/x/time/rate"
+
+	"github.com/aleksander-git/telegram-torrent/internal/settings"
+)
+
+// minBurst is a minimal burst of the limiters, it must fit a chunk the client reads or writes at once
+const minBurst = 256 << 10
+
+// Bandwidth limits download and upload rates of the torrent client
+// and download rates of separate torrents. Rates are in bytes per second, 0 means no limit
+type Bandwidth struct {
+	download *rate.Limiter
+	upload   *rate.Limiter
+
+	torrentLimit func(ctx context.Context, magnetUri string) (int64, error)
+
+	mu sync.Mutex
+	// torrents holds download limiters of the torrents by their info hashes,
+	// a limiter is deleted when storage of its torrent is closed, e.g. the torrent is dropped
+	torrents map[metainfo.Hash]*rate.Limiter
+	storage  storage.ClientImplCloser
+}
+
+var _ Checker = (*Bandwidth)(nil)
+
+func NewBandwidth(download int64, upload int64) *Bandwidth {
+	return &Bandwidth{
+		download: rate.NewLimiter(limit(download), burst(download)),
+		upload:   rate.NewLimiter(limit(upload), burst(upload)),
+		torrents: make(map[metainfo.Hash]*rate.Limiter),
+	}
+}
+
+// WithTorrentLimit sets a function returning download rate of the torrent,
+// e.g. lower for low-priority users. It's called by CheckTorrent
+func (b *Bandwidth) WithTorrentLimit(torrentLimit func(ctx context.Context, magnetUri string) (int64, error)) *Bandwidth {
+	b.torrentLimit = torrentLimit
+
+	return b
+}
+
+// Apply makes the client created with the config use the limits.
+// The storage is wrapped to limit torrents separately,
+// it's file storage in cfg.DataDir if cfg.DefaultStorage is nil
+func (b *Bandwidth) Apply(cfg *torrent.ClientConfig) {
+	cfg.DownloadRateLimiter = b.download
+	cfg.UploadRateLimiter = b.upload
+
+	next := cfg.DefaultStorage
+	if next == nil {
+		// the client closes only the storage it creates itself
+		b.storage = storage.NewFile(cfg.DataDir)
+		next = b.storage
+	}
+
+	cfg.DefaultStorage = &throttledStorage{next: next, bandwidth: b}
+}
+
+// Close closes the storage created by Apply
+func (b *Bandwidth) Close() error {
+	if b.storage == nil {
+		return nil
+	}
+
+	err := b.storage.Close()
+	b.storage = nil
+
+	return err
+}
+
+// Limits returns the global download and upload rates
+func (b *Bandwidth) Limits() (download int64, upload int64) {
+	return rateOf(b.download), rateOf(b.upload)
+}
+
+// SetLimits changes the global download and upload rates
+func (b *Bandwidth) SetLimits(download int64, upload int64) {
+	setRate(b.download, download)
+	setRate(b.upload, upload)
+}
+
+// TorrentLimit returns download rate of the torrent with the info hash
+func (b *Bandwidth) TorrentLimit(infoHash metainfo.Hash) int64 {
+	limiter := b.torrentLimiter(infoHash)
+	if limiter == nil {
+		return 0
+	}
+
+	return rateOf(limiter)
+}
+
+// SetTorrentLimit changes download rate of the torrent with the info hash, 0 removes the limit
+func (b *Bandwidth) SetTorrentLimit(infoHash metainfo.Hash, download int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if download == 0 {
+		delete(b.torrents, infoHash)
+		return
+	}
+
+	if limiter, ok := b.torrents[infoHash]; ok {
+		setRate(limiter, download)
+		return
+	}
+
+	b.torrents[infoHash] = rate.NewLimiter(limit(download), burst(download))
+}
+
+// CheckTorrent sets download rate of the torrent returned by the function passed to WithTorrentLimit.
+// It never rejects torrents
+func (b *Bandwidth) CheckTorrent(ctx context.Context, magnetUri string, info Info) error {
+	if b.torrentLimit == nil {
+		return nil
+	}
+
+	download, err := b.torrentLimit(ctx, magnetUri)
+	if err != nil {
+		return fmt.Errorf("b.torrentLimit(ctx, %q): %w", magnetUri, err)
+	}
+
+	b.SetTorrentLimit(info.InfoHash, download)
+
+	return nil
+}
+
+// Reload applies the global rates from the settings if the changed setting named name holds them.
+// Empty name means that any setting could change.
+//
+// It can be subscribed to settings.Listener to apply changes without a restart
+func (b *Bandwidth) Reload(ctx context.Context, s *settings.Settings, name string) error {
+	if name != "" && name != settings.DownloadRate.Name && name != settings.UploadRate.Name {
+		return nil
+	}
+
+	download, err := s.Int(ctx, settings.DownloadRate, 0)
+	if err != nil {
+		return fmt.Errorf("s.Int(%q): %w", settings.DownloadRate.Name, err)
+	}
+
+	upload, err := s.Int(ctx, settings.UploadRate, 0)
+	if err != nil {
+		return fmt.Errorf("s.Int(%q): %w", settings.UploadRate.Name, err)
+	}
+
+	b.SetLimits(download, upload)
+
+	return nil
+}
+
+func (b *Bandwidth) torrentLimiter(infoHash metainfo.Hash) *rate.Limiter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.torrents[infoHash]
+}
+
+// throttledStorage waits for the torrent download limiter before piece data is written
+type throttledStorage struct {
+	next      storage.ClientImpl
+	bandwidth *Bandwidth
+}
+
+func (s *throttledStorage) OpenTorrent(info *metainfo.Info, infoHash metainfo.Hash) (storage.TorrentImpl, error) {
+	t, err := s.next.OpenTorrent(info, infoHash)
+	if err != nil {
+		return t, err
+	}
+
+	wrap := func(p storage.PieceImpl) storage.PieceImpl {
+		return &throttledPiece{PieceImpl: p, infoHash: infoHash, bandwidth: s.bandwidth}
+	}
+
+	if piece := t.Piece; piece != nil {
+		t.Piece = func(p metainfo.Piece) storage.PieceImpl {
+			return wrap(piece(p))
+		}
+	}
+
+	if pieceWithHash := t.PieceWithHash; pieceWithHash != nil {
+		t.PieceWithHash = func(p metainfo.Piece, pieceHash g.Option[[]byte]) storage.PieceImpl {
+			return wrap(pieceWithHash(p, pieceHash))
+		}
+	}
+
+	next := t.Close
+	t.Close = func() error {
+		s.bandwidth.SetTorrentLimit(infoHash, 0)
+
+		if next == nil {
+			return nil
+		}
+		return next()
+	}
+
+	return t, nil
+}
+
+type throttledPiece struct {
+	storage.PieceImpl
+
+	infoHash  metainfo.Hash
+	bandwidth *Bandwidth
+}
+
+func (p *throttledPiece) WriteAt(b []byte, off int64) (int, error) {
+	if limiter := p.bandwidth.torrentLimiter(p.infoHash); limiter != nil {
+		if err := waitN(limiter, len(b)); err != nil {
+			return 0, err
+		}
+	}
+
+	return p.PieceImpl.WriteAt(b, off)
+}
+
+// waitN waits for n bytes in parts not exceeding the limiter burst
+func waitN(limiter *rate.Limiter, n int) error {
+	for n > 0 {
+		part := min(n, limiter.Burst())
+		if err := limiter.WaitN(context.Background(), part); err != nil {
+			return fmt.Errorf("limiter.WaitN(%d): %w", part, err)
+		}
+		n -= part
+	}
+
+	return nil
+}
+
+func limit(bytesPerSecond int64) rate.Limit {
+	if bytesPerSecond <= 0 {
+		return rate.Inf
+	}
+
+	return rate.Limit(bytesPerSecond)
+}
+
+func burst(bytesPerSecond int64) int {
+	return int(max(bytesPerSecond, minBurst))
+}
+
+func setRate(limiter *rate.Limiter, bytesPerSecond int64) {
+	limiter.SetLimit(limit(bytesPerSecond))
+	limiter.SetBurst(burst(bytesPerSecond))
+}
+
+func rateOf(limiter *rate.Limiter) int64 {
+	if limiter.Limit() == rate.Inf {
+		return 0
+	}
+
+	return int64(limiter.Limit())
+}
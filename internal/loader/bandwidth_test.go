@@ -0,0 +1,86 @@
+package loader_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+	"github.com/aleksander-git/telegram-torrent/internal/settings"
+)
+
+type settingsStore map[string]string
+
+func (s settingsStore) GetSetting(_ context.Context, _ int64, key string) (string, error) {
+	if value, ok := s[key]; ok {
+		return value, nil
+	}
+	return "", sql.ErrNoRows
+}
+
+func (s settingsStore) SetSetting(_ context.Context, _ int64, key string, value string) error {
+	s[key] = value
+	return nil
+}
+
+func (s settingsStore) DeleteSetting(_ context.Context, _ int64, key string) error {
+	delete(s, key)
+	return nil
+}
+
+func TestBandwidth(t *testing.T) {
+	bandwidth := loader.NewBandwidth(0, 1<<20)
+
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = t.TempDir()
+	bandwidth.Apply(cfg)
+	defer bandwidth.Close()
+
+	require.NotNil(t, cfg.DefaultStorage)
+
+	download, upload := bandwidth.Limits()
+	require.Equal(t, int64(0), download)
+	require.Equal(t, int64(1<<20), upload)
+
+	// the client limiters are changed in place
+	bandwidth.SetLimits(2<<20, 0)
+	require.Equal(t, float64(2<<20), float64(cfg.DownloadRateLimiter.Limit()))
+
+	store := settingsStore{"download_rate": "1024", "upload_rate": "2048"}
+	s := settings.New(store)
+
+	require.NoError(t, bandwidth.Reload(context.Background(), s, "torrents_per_day"))
+	download, _ = bandwidth.Limits()
+	require.Equal(t, int64(2<<20), download)
+
+	require.NoError(t, bandwidth.Reload(context.Background(), s, "download_rate"))
+	download, upload = bandwidth.Limits()
+	require.Equal(t, int64(1024), download)
+	require.Equal(t, int64(2048), upload)
+}
+
+func TestBandwidth_TorrentClosed(t *testing.T) {
+	bandwidth := loader.NewBandwidth(0, 0)
+
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = t.TempDir()
+	bandwidth.Apply(cfg)
+	defer bandwidth.Close()
+
+	info := &metainfo.Info{Name: "data.bin", Length: 1, PieceLength: 1, Pieces: make([]byte, 20)}
+	infoHash := metainfo.HashBytes([]byte("data.bin"))
+
+	impl, err := cfg.DefaultStorage.OpenTorrent(info, infoHash)
+	require.NoError(t, err)
+
+	bandwidth.SetTorrentLimit(infoHash, 1<<20)
+	require.Equal(t, int64(1<<20), bandwidth.TorrentLimit(infoHash))
+
+	// the limit is deleted with the dropped torrent
+	require.NoError(t, impl.Close())
+	require.Zero(t, bandwidth.TorrentLimit(infoHash))
+}
@@ -0,0 +1,207 @@
+package loader
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/iplist"
+)
+
+// EncryptionPolicy defines whether peer connections are obfuscated
+type EncryptionPolicy string
+
+const (
+	// EncryptionPrefer obfuscates connections, but accepts plain ones too
+	EncryptionPrefer EncryptionPolicy = "prefer"
+	// EncryptionRequire accepts only obfuscated connections
+	EncryptionRequire EncryptionPolicy = "require"
+	// EncryptionDisable prefers plain connections
+	EncryptionDisable EncryptionPolicy = "disable"
+)
+
+// Config is a configuration of the torrent client
+type Config struct {
+	DataDir string
+	// ListenPort is a port for incoming peer connections, 0 means a random port
+	ListenPort int
+	NoDHT      bool
+	// Encryption is EncryptionPrefer if it's empty
+	Encryption EncryptionPolicy
+	// NoUPnP disables port forwarding with UPnP and NAT-PMP
+	NoUPnP bool
+	// Trackers are added to every magnet link
+	Trackers []string
+	// BlocklistFile is a path to the list of blocked IP ranges in PeerGuardian format, optionally gzipped
+	BlocklistFile string
+	// ProxyURL is a proxy for HTTP trackers and web seeds only,
+	// peer connections, DHT and UDP trackers don't use it
+	ProxyURL string
+}
+
+// ConfigFromEnv reads the configuration from TORRENT_* environment variables:
+//
+//	TORRENT_DATA_DIR    - Config.DataDir
+//	TORRENT_PORT        - Config.ListenPort
+//	TORRENT_NO_DHT      - Config.NoDHT, e.g. true
+//	TORRENT_ENCRYPTION  - Config.Encryption: prefer, require or disable
+//	TORRENT_NO_UPNP     - Config.NoUPnP, e.g. true
+//	TORRENT_TRACKERS    - Config.Trackers separated by commas
+//	TORRENT_BLOCKLIST   - Config.BlocklistFile
+//	TORRENT_PROXY       - Config.ProxyURL
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		DataDir:       os.Getenv("TORRENT_DATA_DIR"),
+		Encryption:    EncryptionPolicy(os.Getenv("TORRENT_ENCRYPTION")),
+		BlocklistFile: os.Getenv("TORRENT_BLOCKLIST"),
+		ProxyURL:      os.Getenv("TORRENT_PROXY"),
+	}
+
+	var err error
+	if port := os.Getenv("TORRENT_PORT"); port != "" {
+		if cfg.ListenPort, err = strconv.Atoi(port); err != nil {
+			return Config{}, fmt.Errorf("unable to parse TORRENT_PORT: %w", err)
+		}
+	}
+
+	if noDHT := os.Getenv("TORRENT_NO_DHT"); noDHT != "" {
+		if cfg.NoDHT, err = strconv.ParseBool(noDHT); err != nil {
+			return Config{}, fmt.Errorf("unable to parse TORRENT_NO_DHT: %w", err)
+		}
+	}
+
+	if noUPnP := os.Getenv("TORRENT_NO_UPNP"); noUPnP != "" {
+		if cfg.NoUPnP, err = strconv.ParseBool(noUPnP); err != nil {
+			return Config{}, fmt.Errorf("unable to parse TORRENT_NO_UPNP: %w", err)
+		}
+	}
+
+	for _, tracker := range strings.Split(os.Getenv("TORRENT_TRACKERS"), ",") {
+		if tracker = strings.TrimSpace(tracker); tracker != "" {
+			cfg.Trackers = append(cfg.Trackers, tracker)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ClientConfig converts the configuration to the anacrolix client one
+func (c Config) ClientConfig() (*torrent.ClientConfig, error) {
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = c.DataDir
+	cfg.ListenPort = c.ListenPort
+	cfg.NoDHT = c.NoDHT
+	cfg.NoDefaultPortForwarding = c.NoUPnP
+
+	switch c.Encryption {
+	case "", EncryptionPrefer:
+		cfg.HeaderObfuscationPolicy = torrent.HeaderObfuscationPolicy{Preferred: true}
+	case EncryptionRequire:
+		cfg.HeaderObfuscationPolicy = torrent.HeaderObfuscationPolicy{Preferred: true, RequirePreferred: true}
+	case EncryptionDisable:
+		cfg.HeaderObfuscationPolicy = torrent.HeaderObfuscationPolicy{}
+	default:
+		return nil, fmt.Errorf("unknown encryption policy %q", c.Encryption)
+	}
+
+	if c.BlocklistFile != "" {
+		blocklist, err := readBlocklist(c.BlocklistFile)
+		if err != nil {
+			return nil, fmt.Errorf("readBlocklist(%q): %w", c.BlocklistFile, err)
+		}
+		cfg.IPBlocklist = blocklist
+	}
+
+	if c.ProxyURL != "" {
+		proxyURL, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("url.Parse(%q): %w", c.ProxyURL, err)
+		}
+		cfg.HTTPProxy = http.ProxyURL(proxyURL)
+	}
+
+	return cfg, nil
+}
+
+// Client is a torrent client adding the configured trackers to every magnet link
+type Client struct {
+	*torrent.Client
+
+	trackers [][]string
+}
+
+var _ TorrentClient = (*Client)(nil)
+
+// NewClient creates the torrent client from the configuration.
+// bandwidth limits the client rates, it's optional
+func NewClient(c Config, bandwidth *Bandwidth) (*Client, error) {
+	cfg, err := c.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("c.ClientConfig(): %w", err)
+	}
+
+	if bandwidth != nil {
+		bandwidth.Apply(cfg)
+	}
+
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		if bandwidth != nil {
+			// the storage created by Apply isn't closed by the client
+			err = errors.Join(err, bandwidth.Close())
+		}
+		return nil, fmt.Errorf("torrent.NewClient(): %w", err)
+	}
+
+	var trackers [][]string
+	if len(c.Trackers) > 0 {
+		// a single tier, so the trackers are tried in order
+		trackers = [][]string{c.Trackers}
+	}
+
+	return &Client{
+		Client:   client,
+		trackers: trackers,
+	}, nil
+}
+
+func (c *Client) AddMagnet(uri string) (*torrent.Torrent, error) {
+	t, err := c.Client.AddMagnet(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.trackers) > 0 {
+		t.AddTrackers(c.trackers)
+	}
+
+	return t, nil
+}
+
+func readBlocklist(path string) (*iplist.IPList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("gzip.NewReader(): %w", err)
+		}
+		defer gz.Close()
+
+		r = gz
+	}
+
+	return iplist.NewFromReader(r)
+}
@@ -0,0 +1,69 @@
+package loader_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("TORRENT_DATA_DIR", "/data")
+	t.Setenv("TORRENT_PORT", "6881")
+	t.Setenv("TORRENT_NO_DHT", "true")
+	t.Setenv("TORRENT_ENCRYPTION", "require")
+	t.Setenv("TORRENT_NO_UPNP", "1")
+	t.Setenv("TORRENT_TRACKERS", "udp://a:1, udp://b:2,")
+	t.Setenv("TORRENT_BLOCKLIST", "")
+	t.Setenv("TORRENT_PROXY", "http://proxy:3128")
+
+	cfg, err := loader.ConfigFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, loader.Config{
+		DataDir:    "/data",
+		ListenPort: 6881,
+		NoDHT:      true,
+		Encryption: loader.EncryptionRequire,
+		NoUPnP:     true,
+		Trackers:   []string{"udp://a:1", "udp://b:2"},
+		ProxyURL:   "http://proxy:3128",
+	}, cfg)
+
+	t.Setenv("TORRENT_PORT", "port")
+	_, err = loader.ConfigFromEnv()
+	require.Error(t, err)
+}
+
+func TestConfig_ClientConfig(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.p2p")
+	require.NoError(t, os.WriteFile(blocklist, []byte("bad peers:10.0.0.0-10.0.0.255\n"), 0o644))
+
+	cfg, err := loader.Config{
+		DataDir:       "/data",
+		ListenPort:    6881,
+		NoDHT:         true,
+		Encryption:    loader.EncryptionRequire,
+		NoUPnP:        true,
+		BlocklistFile: blocklist,
+		ProxyURL:      "http://proxy:3128",
+	}.ClientConfig()
+	require.NoError(t, err)
+
+	require.Equal(t, "/data", cfg.DataDir)
+	require.Equal(t, 6881, cfg.ListenPort)
+	require.True(t, cfg.NoDHT)
+	require.True(t, cfg.NoDefaultPortForwarding)
+	require.Equal(t, torrent.HeaderObfuscationPolicy{Preferred: true, RequirePreferred: true}, cfg.HeaderObfuscationPolicy)
+	require.NotNil(t, cfg.HTTPProxy)
+
+	_, blocked := cfg.IPBlocklist.Lookup(net.ParseIP("10.0.0.1"))
+	require.True(t, blocked)
+
+	_, err = loader.Config{Encryption: "sometimes"}.ClientConfig()
+	require.Error(t, err)
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
 )
 
 type Loader struct {
@@ -15,6 +17,31 @@ type Loader struct {
 	client TorrentClient
 
 	timeout time.Duration
+
+	checks []Checker
+	seeder *Seeder
+
+	mu sync.Mutex
+	// loaded holds the loaded torrents by their magnet links until Seed or Drop is called
+	loaded map[string]*torrent.Torrent
+}
+
+// Info is metadata of the torrent
+type Info struct {
+	InfoHash metainfo.Hash
+	Name     string
+	Size     int64
+}
+
+// Checker decides whether the torrent can be downloaded once its metadata is known
+type Checker interface {
+	CheckTorrent(ctx context.Context, magnetUri string, info Info) error
+}
+
+// Releaser is implemented by checkers holding resources for the accepted torrents,
+// e.g. reserved space. Release is called when the torrent isn't loaded after the checks
+type Releaser interface {
+	Release(magnetUri string)
 }
 
 type TorrentClient interface {
@@ -26,14 +53,35 @@ func New(log *slog.Logger, client TorrentClient, timeout time.Duration) (*Loader
 		log:     log,
 		client:  client,
 		timeout: timeout,
+		loaded:  make(map[string]*torrent.Torrent),
 	}, nil
 }
 
+// WithSeeder sets the seeder Seed passes uploaded torrents to.
+// Without a seeder uploaded torrents are dropped
+func (l *Loader) WithSeeder(seeder *Seeder) *Loader {
+	l.seeder = seeder
+
+	return l
+}
+
+// WithCheck adds checks called in order before the download is started,
+// the torrent is dropped if any of them fails. Checks implementing Releaser
+// are released when the torrent is rejected or isn't loaded
+func (l *Loader) WithCheck(checks ...Checker) *Loader {
+	l.checks = append(l.checks, checks...)
+
+	return l
+}
+
+// Load downloads the torrent. onLoadTick is called every loadTickInterval
+// with the current progress, including waiting for metadata.
+// The loaded torrent is kept until Seed or Drop is called
 func (l *Loader) Load(
 	ctx context.Context,
 	magnetUri string,
 	loadTickInterval time.Duration,
-	onLoadTick func(ctx context.Context, totalBytes, bytesCompleted int64),
+	onLoadTick func(ctx context.Context, progress Progress),
 ) (bytesSize int64, err error) {
 	const src = "Loader.Load"
 	log := l.log.With(slog.String("src", src))
@@ -47,46 +95,145 @@ func (l *Loader) Load(
 	ctx, cancel := context.WithTimeout(ctx, l.timeout)
 	defer cancel()
 
-	for processing := true; processing ; {
+	meter := newProgressMeter(torrentFile)
+	ticker := time.NewTicker(loadTickInterval)
+	defer ticker.Stop()
+
+	for processing := true; processing; {
 		select {
 		case <-ctx.Done():
 			return 0, fmt.Errorf("failed to get info: %w", ctx.Err())
 		case <-torrentFile.GotInfo():
-			fmt.Println("got info", torrentFile.Info().Length)
+			log.Debug("got info",
+				slog.String("uri", magnetUri),
+				slog.Int64("size", torrentFile.Info().TotalLength()),
+			)
 			processing = false
+		case now := <-ticker.C:
+			if onLoadTick != nil {
+				onLoadTick(ctx, meter.progress(now))
+			}
 		}
 	}
 
-	torrentFile.DownloadAll()
+	info := Info{
+		InfoHash: torrentFile.InfoHash(),
+		Name:     torrentFile.Name(),
+		Size:     torrentFile.Info().TotalLength(),
+	}
 
-	totalBytes := torrentFile.Info().Length
-	ticker := time.NewTicker(loadTickInterval)
-	defer ticker.Stop()
+	defer func() {
+		if err != nil {
+			torrentFile.Drop()
+			l.release(magnetUri)
+		}
+	}()
+
+	for _, check := range l.checks {
+		if err := check.CheckTorrent(ctx, magnetUri, info); err != nil {
+			return 0, fmt.Errorf("torrent is rejected: %w", err)
+		}
+	}
+
+	torrentFile.DownloadAll()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return 0, fmt.Errorf("file loading failed: %w", ctx.Err())
-		case <-ticker.C:
-			bytesCompleted := torrentFile.BytesCompleted()
+		case now := <-ticker.C:
+			progress := meter.progress(now)
 
 			if onLoadTick != nil {
-				onLoadTick(ctx, totalBytes, bytesCompleted)
+				onLoadTick(ctx, progress)
 			}
 
-			if bytesCompleted >= totalBytes {
+			if progress.Phase == PhaseDone {
 				log.Debug("torrent loaded",
 					slog.String("uri", magnetUri),
-					slog.Int64("size", totalBytes),
+					slog.Int64("size", progress.TotalBytes),
 				)
-				return totalBytes, nil
+
+				l.mu.Lock()
+				l.loaded[magnetUri] = torrentFile
+				l.mu.Unlock()
+
+				return progress.TotalBytes, nil
 			} else {
 				log.Debug("torrent loading...",
-					slog.Float64("percentage", float64(bytesCompleted)/float64(totalBytes)*100),
-					slog.Int64("bytesCompleted", bytesCompleted),
-					slog.Int64("totalBytes", totalBytes),
+					slog.String("phase", string(progress.Phase)),
+					slog.Float64("percentage", progress.Percent()),
+					slog.Int64("bytesCompleted", progress.CompletedBytes),
+					slog.Int64("totalBytes", progress.TotalBytes),
+					slog.Float64("downloadSpeed", progress.DownloadSpeed),
+					slog.Duration("eta", progress.ETA),
+					slog.Int("peers", progress.Peers),
 				)
 			}
 		}
 	}
 }
+
+// Seed passes the loaded torrent to the seeder once its data is uploaded,
+// the seeder drops it and removes the data when seeding is finished.
+// Without a seeder the torrent is dropped at once
+func (l *Loader) Seed(magnetUri string) {
+	if l.seeder == nil {
+		l.Drop(magnetUri)
+		return
+	}
+
+	torrentFile, ok := l.take(magnetUri)
+	if !ok {
+		return
+	}
+
+	l.seeder.Seed(magnetUri, &seedingTorrent{
+		Torrent: torrentFile,
+		release: func() { l.release(magnetUri) },
+	})
+}
+
+// Drop drops the loaded torrent whose data isn't uploaded
+// and releases resources held by the checks for it
+func (l *Loader) Drop(magnetUri string) {
+	torrentFile, ok := l.take(magnetUri)
+	if !ok {
+		return
+	}
+
+	torrentFile.Drop()
+	l.release(magnetUri)
+}
+
+func (l *Loader) take(magnetUri string) (*torrent.Torrent, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	torrentFile, ok := l.loaded[magnetUri]
+	delete(l.loaded, magnetUri)
+
+	return torrentFile, ok
+}
+
+// seedingTorrent releases resources held by the checks when the seeder drops the torrent,
+// whether its data is removed or not
+type seedingTorrent struct {
+	*torrent.Torrent
+
+	release func()
+}
+
+func (t *seedingTorrent) Drop() {
+	t.Torrent.Drop()
+	t.release()
+}
+
+// release releases resources held by the checks for the torrent
+func (l *Loader) release(magnetUri string) {
+	for _, check := range l.checks {
+		if releaser, ok := check.(Releaser); ok {
+			releaser.Release(magnetUri)
+		}
+	}
+}
@@ -40,8 +40,10 @@ func TestLoader_Load(t *testing.T) {
 			l, err := loader.New(slog.Default(), client, test.timeout)
 			require.NoError(t, err, "failed to init loader")
 
-			size, err := l.Load(ctx, test.uri, 2 * time.Second, func(_ context.Context, totalBytes, bytesCompleted int64) {
-				fmt.Printf ("downloaded %d bytes of %d\n", bytesCompleted, totalBytes)
+			size, err := l.Load(ctx, test.uri, 2 * time.Second, func(_ context.Context, progress loader.Progress) {
+				fmt.Printf("%s: downloaded %d bytes of %d, %.0f B/s, eta %s, peers %d, pieces %d/%d\n",
+					progress.Phase, progress.CompletedBytes, progress.TotalBytes, progress.DownloadSpeed,
+					progress.ETA, progress.Peers, progress.PiecesCompleted, progress.PiecesTotal)
             })
 			require.NoError(t, err, "failed to download file from uri %q", test.uri)
 
@@ -0,0 +1,170 @@
+package loader_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+	"github.com/aleksander-git/telegram-torrent/internal/storage"
+)
+
+// offlineClient creates a torrent client without network and a torrent of a single file.
+// The file data is written to the client data directory if complete is true
+func offlineClient(t *testing.T, complete bool) (client *torrent.Client, magnetUri string, dataDir string) {
+	t.Helper()
+
+	source := filepath.Join(t.TempDir(), "data.bin")
+	data := make([]byte, 100<<10)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	require.NoError(t, os.WriteFile(source, data, 0o644))
+
+	info := metainfo.Info{PieceLength: 16 << 10}
+	require.NoError(t, info.BuildFromFilePath(source))
+
+	infoBytes, err := bencode.Marshal(info)
+	require.NoError(t, err)
+
+	mi := metainfo.MetaInfo{InfoBytes: infoBytes}
+
+	dataDir = t.TempDir()
+	if complete {
+		require.NoError(t, os.WriteFile(filepath.Join(dataDir, info.Name), data, 0o644))
+	}
+
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = dataDir
+	cfg.NoDHT = true
+	cfg.DisableTrackers = true
+	cfg.NoDefaultPortForwarding = true
+	cfg.ListenPort = 0
+
+	client, err = torrent.NewClient(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	// the torrent is added with its info, so the magnet link doesn't wait for peers
+	_, err = client.AddTorrent(&mi)
+	require.NoError(t, err)
+
+	return client, mi.Magnet(nil, &info).String(), dataDir
+}
+
+type rejectCheck struct{}
+
+func (rejectCheck) CheckTorrent(_ context.Context, _ string, _ loader.Info) error {
+	return errors.New("rejected")
+}
+
+func TestLoader_Load_Release(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []loader.Checker
+	}{
+		{
+			name:   "rejected",
+			checks: []loader.Checker{rejectCheck{}},
+		}, {
+			name: "timeout",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, magnetUri, dataDir := offlineClient(t, false)
+
+			manager := storage.New(slog.Default(), dataDir, nil)
+
+			l, err := loader.New(slog.Default(), client, 500*time.Millisecond)
+			require.NoError(t, err)
+			l.WithCheck(manager).WithCheck(test.checks...)
+
+			reserved := false
+			_, err = l.Load(context.Background(), magnetUri, 50*time.Millisecond, func(_ context.Context, progress loader.Progress) {
+				reserved = reserved || manager.Reserved() > 0
+			})
+			require.Error(t, err)
+
+			if len(test.checks) == 0 {
+				require.True(t, reserved, "space is not reserved while the torrent is loaded")
+			}
+			require.Zero(t, manager.Reserved())
+		})
+	}
+}
+
+func TestLoader_Seed(t *testing.T) {
+	tests := []struct {
+		name   string
+		seeder bool
+		remove bool
+	}{
+		{
+			name:   "remove",
+			seeder: true,
+			remove: true,
+		}, {
+			name:   "keep_data",
+			seeder: true,
+		}, {
+			name: "no_seeder",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, magnetUri, dataDir := offlineClient(t, true)
+
+			manager := storage.New(slog.Default(), dataDir, nil)
+
+			l, err := loader.New(slog.Default(), client, 10*time.Second)
+			require.NoError(t, err)
+			l.WithCheck(manager)
+
+			removed := 0
+			if test.seeder {
+				seeder := loader.NewSeeder(slog.Default(), loader.NoSeeding)
+				if test.remove {
+					seeder.WithRemove(func(magnetUri string, name string) error {
+						removed++
+						return manager.Remove(magnetUri, name)
+					})
+				}
+				l.WithSeeder(seeder)
+			}
+
+			size, err := l.Load(context.Background(), magnetUri, 50*time.Millisecond, nil)
+			require.NoError(t, err)
+			require.EqualValues(t, 100<<10, size)
+
+			// the data is kept for the upload
+			require.Zero(t, removed)
+			require.FileExists(t, filepath.Join(dataDir, "data.bin"))
+			require.NotZero(t, manager.Reserved())
+
+			l.Seed(magnetUri)
+
+			// the torrent is dropped from the client and its space is released
+			require.Empty(t, client.Torrents())
+			require.Zero(t, manager.Reserved())
+
+			if test.remove {
+				require.Equal(t, 1, removed)
+				require.NoFileExists(t, filepath.Join(dataDir, "data.bin"))
+			} else {
+				require.FileExists(t, filepath.Join(dataDir, "data.bin"))
+			}
+		})
+	}
+}
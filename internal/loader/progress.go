@@ -0,0 +1,194 @@
+package loader
+
+import (
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// Phase is a stage of the torrent loading
+type Phase string
+
+const (
+	// PhaseMetadata is waiting for the torrent info from peers, sizes and pieces are unknown
+	PhaseMetadata Phase = "metadata"
+	// PhaseDownloading is receiving the data from peers, ETA is estimated only in this phase
+	PhaseDownloading Phase = "downloading"
+	// PhaseVerifying is hashing of the data, either existing before the download or downloaded completely.
+	// Pieces hashed while the download is in progress, including re-hashing, are reported as PhaseDownloading
+	PhaseVerifying Phase = "verifying"
+	// PhaseDone is the state of the torrent with all pieces completed and verified
+	PhaseDone Phase = "done"
+)
+
+// speedSmoothing is a weight of the latest speed sample in the moving average
+const speedSmoothing = 0.3
+
+// Progress is a state of the torrent loading
+type Progress struct {
+	// Phase goes from PhaseMetadata to PhaseDone, PhaseVerifying can appear
+	// before PhaseDownloading and before PhaseDone
+	Phase Phase
+
+	// TotalBytes and CompletedBytes are 0 until metadata is received
+	TotalBytes     int64
+	CompletedBytes int64
+
+	// DownloadSpeed and UploadSpeed are smoothed rates in bytes per second
+	DownloadSpeed float64
+	UploadSpeed   float64
+	// ETA is an estimated time left, 0 if it's unknown
+	ETA time.Duration
+
+	// Peers is a number of active peer connections, Seeds is a number of them having all pieces
+	Peers int
+	Seeds int
+
+	PiecesTotal     int
+	PiecesCompleted int
+	// PiecesAvailable is a number of pieces completed or owned by connected peers,
+	// the torrent can't be completed while it's less than PiecesTotal
+	PiecesAvailable int
+}
+
+// Percent returns the completed part of the torrent in percents
+func (p Progress) Percent() float64 {
+	if p.TotalBytes == 0 {
+		return 0
+	}
+
+	return float64(p.CompletedBytes) / float64(p.TotalBytes) * 100
+}
+
+// progressMeter measures progress of the torrent between ticks
+type progressMeter struct {
+	torrent *torrent.Torrent
+
+	measured       bool
+	last           time.Time
+	lastDownloaded int64
+	lastUploaded   int64
+
+	downloadSpeed float64
+	uploadSpeed   float64
+}
+
+func newProgressMeter(t *torrent.Torrent) *progressMeter {
+	return &progressMeter{torrent: t}
+}
+
+func (m *progressMeter) progress(now time.Time) Progress {
+	stats := m.torrent.Stats()
+	downloaded := stats.BytesReadData.Int64()
+	uploaded := stats.BytesWrittenData.Int64()
+
+	m.measure(now, downloaded, uploaded)
+
+	p := Progress{
+		Phase:         PhaseMetadata,
+		DownloadSpeed: m.downloadSpeed,
+		UploadSpeed:   m.uploadSpeed,
+		Peers:         stats.ActivePeers,
+		Seeds:         stats.ConnectedSeeders,
+	}
+
+	if m.torrent.Info() == nil {
+		return p
+	}
+
+	p.TotalBytes = m.torrent.Length()
+	p.CompletedBytes = m.torrent.BytesCompleted()
+	p.PiecesTotal = m.torrent.NumPieces()
+
+	available := make([]bool, p.PiecesTotal)
+
+	hashing := false
+	index := 0
+	for _, run := range m.torrent.PieceStateRuns() {
+		if run.Complete {
+			p.PiecesCompleted += run.Length
+			for i := index; i < index+run.Length && i < len(available); i++ {
+				available[i] = true
+			}
+		}
+
+		hashing = hashing || run.Hashing || run.QueuedForHash || run.Marking
+		index += run.Length
+	}
+
+	for _, conn := range m.torrent.PeerConns() {
+		conn.PeerPieces().Iterate(func(piece uint32) bool {
+			if int(piece) < len(available) {
+				available[piece] = true
+			}
+			return true
+		})
+	}
+
+	for _, ok := range available {
+		if ok {
+			p.PiecesAvailable++
+		}
+	}
+
+	p.Phase = phase(p, hashing, downloaded)
+	p.ETA = eta(p)
+
+	return p
+}
+
+// phase selects the phase of the torrent with metadata. hashing reports whether any piece is hashed,
+// downloaded is number of the data bytes received from peers
+func phase(p Progress, hashing bool, downloaded int64) Phase {
+	switch {
+	case p.PiecesCompleted == p.PiecesTotal:
+		return PhaseDone
+	// data is hashed before the download starts or after it's downloaded completely,
+	// otherwise the downloaded pieces are hashed as they are received
+	case hashing && (downloaded == 0 || p.CompletedBytes >= p.TotalBytes):
+		return PhaseVerifying
+	default:
+		return PhaseDownloading
+	}
+}
+
+// eta estimates time left of the download at the current speed, 0 if it's unknown
+func eta(p Progress) time.Duration {
+	if p.Phase != PhaseDownloading || p.DownloadSpeed <= 0 {
+		return 0
+	}
+
+	left := float64(p.TotalBytes - p.CompletedBytes)
+	return time.Duration(left / p.DownloadSpeed * float64(time.Second))
+}
+
+// measure updates exponential moving averages of the speeds
+func (m *progressMeter) measure(now time.Time, downloaded int64, uploaded int64) {
+	defer func() {
+		m.last = now
+		m.lastDownloaded = downloaded
+		m.lastUploaded = uploaded
+	}()
+
+	if m.last.IsZero() {
+		return
+	}
+
+	elapsed := now.Sub(m.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	downloadSpeed := float64(downloaded-m.lastDownloaded) / elapsed
+	uploadSpeed := float64(uploaded-m.lastUploaded) / elapsed
+
+	if !m.measured {
+		m.measured = true
+		m.downloadSpeed = downloadSpeed
+		m.uploadSpeed = uploadSpeed
+		return
+	}
+
+	m.downloadSpeed = speedSmoothing*downloadSpeed + (1-speedSmoothing)*m.downloadSpeed
+	m.uploadSpeed = speedSmoothing*uploadSpeed + (1-speedSmoothing)*m.uploadSpeed
+}
@@ -0,0 +1,128 @@
+package loader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressMeter_Measure(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		at         time.Duration
+		downloaded int64
+		uploaded   int64
+		download   float64
+		upload     float64
+	}{
+		{
+			// the first sample has nothing to compare with
+			name: "first_sample",
+		}, {
+			name:       "first_speed",
+			at:         time.Second,
+			downloaded: 1000,
+			uploaded:   100,
+			download:   1000,
+			upload:     100,
+		}, {
+			name:       "smoothed",
+			at:         2 * time.Second,
+			downloaded: 5000,
+			uploaded:   100,
+			download:   0.3*4000 + 0.7*1000,
+			upload:     0.7 * 100,
+		}, {
+			name:       "no_time_passed",
+			at:         2 * time.Second,
+			downloaded: 9000,
+			uploaded:   100,
+			download:   0.3*4000 + 0.7*1000,
+			upload:     0.7 * 100,
+		},
+	}
+
+	// the samples are measured in order by the same meter
+	m := &progressMeter{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m.measure(start.Add(test.at), test.downloaded, test.uploaded)
+
+			require.InDelta(t, test.download, m.downloadSpeed, 1e-9)
+			require.InDelta(t, test.upload, m.uploadSpeed, 1e-9)
+		})
+	}
+}
+
+func TestPhase(t *testing.T) {
+	tests := []struct {
+		name       string
+		progress   Progress
+		hashing    bool
+		downloaded int64
+		phase      Phase
+	}{
+		{
+			name:     "done",
+			progress: Progress{TotalBytes: 400, CompletedBytes: 400, PiecesTotal: 4, PiecesCompleted: 4},
+			phase:    PhaseDone,
+		}, {
+			name:     "existing_data",
+			progress: Progress{TotalBytes: 400, CompletedBytes: 100, PiecesTotal: 4, PiecesCompleted: 1},
+			hashing:  true,
+			phase:    PhaseVerifying,
+		}, {
+			name:       "downloading",
+			progress:   Progress{TotalBytes: 400, CompletedBytes: 100, PiecesTotal: 4, PiecesCompleted: 1},
+			downloaded: 100,
+			phase:      PhaseDownloading,
+		}, {
+			name:       "received_piece",
+			progress:   Progress{TotalBytes: 400, CompletedBytes: 100, PiecesTotal: 4, PiecesCompleted: 1},
+			hashing:    true,
+			downloaded: 200,
+			phase:      PhaseDownloading,
+		}, {
+			name:       "downloaded",
+			progress:   Progress{TotalBytes: 400, CompletedBytes: 400, PiecesTotal: 4, PiecesCompleted: 3},
+			hashing:    true,
+			downloaded: 400,
+			phase:      PhaseVerifying,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.phase, phase(test.progress, test.hashing, test.downloaded))
+		})
+	}
+}
+
+func TestETA(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress Progress
+		eta      time.Duration
+	}{
+		{
+			name:     "downloading",
+			progress: Progress{Phase: PhaseDownloading, TotalBytes: 4000, CompletedBytes: 1000, DownloadSpeed: 100},
+			eta:      30 * time.Second,
+		}, {
+			name:     "stalled",
+			progress: Progress{Phase: PhaseDownloading, TotalBytes: 4000, CompletedBytes: 1000},
+		}, {
+			name:     "verifying",
+			progress: Progress{Phase: PhaseVerifying, TotalBytes: 4000, CompletedBytes: 1000, DownloadSpeed: 100},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.eta, eta(test.progress))
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package loader_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+)
+
+func TestProgress_Percent(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress loader.Progress
+		percent  float64
+	}{
+		{
+			name:     "metadata",
+			progress: loader.Progress{Phase: loader.PhaseMetadata},
+			percent:  0,
+		},
+		{
+			name:     "downloading",
+			progress: loader.Progress{Phase: loader.PhaseDownloading, TotalBytes: 400, CompletedBytes: 100},
+			percent:  25,
+		},
+		{
+			name:     "done",
+			progress: loader.Progress{Phase: loader.PhaseDone, TotalBytes: 400, CompletedBytes: 400},
+			percent:  100,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.percent, test.progress.Percent())
+		})
+	}
+}
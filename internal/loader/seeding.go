@@ -0,0 +1,181 @@
+package loader
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// SeedingPolicy defines how long downloaded torrents are seeded.
+// Seeding stops when any of the limits is reached, zero policy disables seeding
+type SeedingPolicy struct {
+	// Ratio is a ratio of uploaded bytes to the torrent size, 0 means no ratio limit
+	Ratio float64
+	// Duration is a time the torrent is seeded for, 0 means no time limit
+	Duration time.Duration
+}
+
+// NoSeeding drops torrents as soon as they are downloaded
+var NoSeeding = SeedingPolicy{}
+
+// Disabled reports whether torrents aren't seeded at all
+func (p SeedingPolicy) Disabled() bool {
+	return p.Ratio <= 0 && p.Duration <= 0
+}
+
+// Satisfied reports whether the torrent of the size can stop seeding
+func (p SeedingPolicy) Satisfied(size int64, uploaded int64, seeding time.Duration) bool {
+	if p.Disabled() {
+		return true
+	}
+
+	if p.Ratio > 0 && size > 0 && float64(uploaded)/float64(size) >= p.Ratio {
+		return true
+	}
+
+	return p.Duration > 0 && seeding >= p.Duration
+}
+
+// SeedingTorrent is a downloaded torrent, it's implemented by *torrent.Torrent.
+// Torrents passed by Loader.Seed release reserved resources when they are dropped
+type SeedingTorrent interface {
+	Name() string
+	Length() int64
+	Stats() torrent.TorrentStats
+	Drop()
+}
+
+type seed struct {
+	magnetUri string
+	torrent   SeedingTorrent
+	started   time.Time
+}
+
+// Seeder seeds downloaded torrents according to the policy
+// and drops them when the policy is satisfied
+type Seeder struct {
+	log    *slog.Logger
+	policy SeedingPolicy
+	remove func(magnetUri string, name string) error
+
+	mu    sync.Mutex
+	seeds map[string]seed
+
+	now func() time.Time
+}
+
+func NewSeeder(log *slog.Logger, policy SeedingPolicy) *Seeder {
+	return &Seeder{
+		log:    log,
+		policy: policy,
+		seeds:  make(map[string]seed),
+		now:    time.Now,
+	}
+}
+
+// WithRemove sets a function deleting data of the dropped torrents,
+// e.g. storage.Manager.Remove
+func (s *Seeder) WithRemove(remove func(magnetUri string, name string) error) *Seeder {
+	s.remove = remove
+
+	return s
+}
+
+// Seed starts tracking the downloaded torrent, it's called by Loader.Seed
+// after the torrent data is uploaded
+func (s *Seeder) Seed(magnetUri string, t SeedingTorrent) {
+	if s.policy.Disabled() {
+		s.drop(seed{magnetUri: magnetUri, torrent: t})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seeds[magnetUri] = seed{
+		magnetUri: magnetUri,
+		torrent:   t,
+		started:   s.now(),
+	}
+}
+
+// Seeding returns number of the torrents being seeded
+func (s *Seeder) Seeding() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.seeds)
+}
+
+// Check drops the torrents whose policy is satisfied
+func (s *Seeder) Check() {
+	now := s.now()
+
+	var done []seed
+
+	s.mu.Lock()
+	for magnetUri, seed := range s.seeds {
+		uploaded := uploadedBytes(seed.torrent)
+		if s.policy.Satisfied(seed.torrent.Length(), uploaded, now.Sub(seed.started)) {
+			done = append(done, seed)
+			delete(s.seeds, magnetUri)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, seed := range done {
+		s.drop(seed)
+	}
+}
+
+// Run calls Check every interval until ctx is done
+func (s *Seeder) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.Check()
+		}
+	}
+}
+
+func (s *Seeder) drop(seed seed) {
+	const src = "Seeder.drop"
+	log := s.log.With(
+		slog.String("src", src),
+	)
+
+	name := seed.torrent.Name()
+	uploaded := uploadedBytes(seed.torrent)
+
+	seed.torrent.Drop()
+
+	log.Debug("seeding finished",
+		slog.String("uri", seed.magnetUri),
+		slog.Int64("uploaded", uploaded),
+	)
+
+	if s.remove == nil {
+		return
+	}
+
+	if err := s.remove(seed.magnetUri, name); err != nil {
+		log.Error("cannot remove torrent data",
+			slog.String("uri", seed.magnetUri),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// uploadedBytes returns number of the torrent data bytes sent to peers
+func uploadedBytes(t SeedingTorrent) int64 {
+	stats := t.Stats()
+	return stats.BytesWrittenData.Int64()
+}
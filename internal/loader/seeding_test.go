@@ -0,0 +1,87 @@
+package loader_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+)
+
+func TestSeedingPolicy_Satisfied(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   loader.SeedingPolicy
+		uploaded int64
+		seeding  time.Duration
+		want     bool
+	}{
+		{
+			name:   "no_seeding",
+			policy: loader.NoSeeding,
+			want:   true,
+		}, {
+			name:     "ratio_not_reached",
+			policy:   loader.SeedingPolicy{Ratio: 1.5},
+			uploaded: 100,
+			seeding:  time.Hour,
+		}, {
+			name:     "ratio_reached",
+			policy:   loader.SeedingPolicy{Ratio: 1.5},
+			uploaded: 150,
+			want:     true,
+		}, {
+			name:    "duration_reached",
+			policy:  loader.SeedingPolicy{Ratio: 1.5, Duration: time.Hour},
+			seeding: time.Hour,
+			want:    true,
+		}, {
+			name:     "duration_not_reached",
+			policy:   loader.SeedingPolicy{Duration: time.Hour},
+			uploaded: 1000,
+			seeding:  time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.want, test.policy.Satisfied(100, test.uploaded, test.seeding))
+		})
+	}
+}
+
+type fakeTorrent struct {
+	stats   torrent.TorrentStats
+	dropped bool
+}
+
+func (f *fakeTorrent) Name() string                { return "fake" }
+func (f *fakeTorrent) Length() int64               { return 100 }
+func (f *fakeTorrent) Stats() torrent.TorrentStats { return f.stats }
+func (f *fakeTorrent) Drop()                       { f.dropped = true }
+
+func TestSeeder(t *testing.T) {
+	var removed []string
+	seeder := loader.NewSeeder(slog.Default(), loader.SeedingPolicy{Ratio: 1}).
+		WithRemove(func(magnetUri string, name string) error {
+			removed = append(removed, name)
+			return nil
+		})
+
+	fake := &fakeTorrent{}
+	seeder.Seed("magnet:?dn=fake", fake)
+
+	fake.stats.BytesWrittenData.Add(50)
+	seeder.Check()
+	require.False(t, fake.dropped)
+	require.Equal(t, 1, seeder.Seeding())
+
+	fake.stats.BytesWrittenData.Add(50)
+	seeder.Check()
+	require.True(t, fake.dropped)
+	require.Equal(t, 0, seeder.Seeding())
+	require.Equal(t, []string{"fake"}, removed)
+}
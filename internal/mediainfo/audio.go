@@ -0,0 +1,69 @@
+package mediainfo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AudioTags is a metadata of an audio file
+type AudioTags struct {
+	Title     string
+	Performer string
+	Album     string
+
+	Track      int
+	TrackTotal int
+	Disc       int
+
+	Duration time.Duration
+
+	// Cover is an embedded cover art image, nil if there is none
+	Cover []byte
+}
+
+// ReadAudioTags reads tags of MP3 (ID3v2), FLAC (Vorbis comments) or M4A (iTunes atoms) file.
+// The format is detected by the content, not by the file extension.
+//
+// Position of r is restored after reading
+func ReadAudioTags(r io.ReadSeeker) (tags AudioTags, err error) {
+	section, restore, err := sectionOf(r)
+	if err != nil {
+		return AudioTags{}, err
+	}
+	defer func() {
+		if restoreErr := restore(); restoreErr != nil && err == nil {
+			err = fmt.Errorf("failed to restore reader position: %w", restoreErr)
+		}
+	}()
+
+	head := make([]byte, 12)
+	if _, err := section.ReadAt(head, 0); err != nil {
+		return AudioTags{}, fmt.Errorf("%w: file is too short", ErrUnsupported)
+	}
+
+	switch {
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return readMP3(section)
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return readFLAC(section)
+	case bytes.Equal(head[4:8], []byte("ftyp")):
+		return readM4A(section)
+	case isFrameSync(head):
+		return readMP3(section)
+	default:
+		return AudioTags{}, ErrUnsupported
+	}
+}
+
+// parseNumberPair parses values like "3/12" used for track and disc numbers
+func parseNumberPair(s string) (number, total int) {
+	numberStr, totalStr, _ := strings.Cut(strings.TrimSpace(s), "/")
+	number, _ = strconv.Atoi(strings.TrimSpace(numberStr))
+	total, _ = strconv.Atoi(strings.TrimSpace(totalStr))
+
+	return number, total
+}
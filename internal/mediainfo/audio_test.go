@@ -0,0 +1,199 @@
+package mediainfo_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/aleksander-git/telegram-torrent/internal/mediainfo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAudioTags(t *testing.T) {
+	var tests = []struct {
+		name string
+		file []byte
+		want mediainfo.AudioTags
+	}{
+		{
+			name: "id3v23_latin1",
+			file: id3Tag(3,
+				id3TextFrame(3, "TIT2", 0, "Merry Xmas"),
+				id3TextFrame(3, "TPE1", 0, "Choir"),
+				id3TextFrame(3, "TALB", 0, "Holidays"),
+				id3TextFrame(3, "TRCK", 0, "3/12"),
+				id3TextFrame(3, "TPOS", 0, "1/2"),
+				id3TextFrame(3, "TLEN", 0, "61500"),
+			),
+			want: mediainfo.AudioTags{
+				Title:      "Merry Xmas",
+				Performer:  "Choir",
+				Album:      "Holidays",
+				Track:      3,
+				TrackTotal: 12,
+				Disc:       1,
+				Duration:   61500 * time.Millisecond,
+			},
+		},
+		{
+			name: "id3v24_utf16_album_artist",
+			file: id3Tag(4,
+				id3TextFrame(4, "TIT2", 1, "Ёлка"),
+				id3TextFrame(4, "TPE2", 3, "Хор"),
+				id3TextFrame(4, "TRCK", 3, "7"),
+			),
+			want: mediainfo.AudioTags{
+				Title:     "Ёлка",
+				Performer: "Хор",
+				Track:     7,
+			},
+		},
+		{
+			name: "id3v23_cbr_duration",
+			file: append(
+				id3Tag(3, id3TextFrame(3, "TIT2", 0, "Beep")),
+				// MPEG1 Layer III, 128 kbit/s, 44100 Hz, stereo; 16000 bytes of audio is 1 second
+				mpegFrames(16000)...,
+			),
+			want: mediainfo.AudioTags{
+				Title:    "Beep",
+				Duration: time.Second,
+			},
+		},
+		{
+			name: "flac",
+			file: flacFile(44100, 44100*90, "TITLE=Song", "ARTIST=Band", "ALBUM=Record", "TRACKNUMBER=2", "TRACKTOTAL=9"),
+			want: mediainfo.AudioTags{
+				Title:      "Song",
+				Performer:  "Band",
+				Album:      "Record",
+				Track:      2,
+				TrackTotal: 9,
+				Duration:   90 * time.Second,
+			},
+		},
+		{
+			name: "m4a",
+			file: m4aFile(1000, 125500, map[string][]byte{
+				"\xa9nam": []byte("Track"),
+				"aART":    []byte("Artist"),
+				"\xa9alb": []byte("Album"),
+				"trkn":    {0, 0, 0, 4, 0, 10, 0, 0},
+				"disk":    {0, 0, 0, 2, 0, 2},
+			}),
+			want: mediainfo.AudioTags{
+				Title:      "Track",
+				Performer:  "Artist",
+				Album:      "Album",
+				Track:      4,
+				TrackTotal: 10,
+				Disc:       2,
+				Duration:   125500 * time.Millisecond,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tags, err := mediainfo.ReadAudioTags(bytes.NewReader(test.file))
+			require.NoError(t, err)
+			require.Equal(t, test.want, tags)
+		})
+	}
+}
+
+func TestReadAudioTags_Unsupported(t *testing.T) {
+	_, err := mediainfo.ReadAudioTags(bytes.NewReader([]byte("hello, world!")))
+	require.ErrorIs(t, err, mediainfo.ErrUnsupported)
+}
+
+func syncsafe(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+func id3Tag(version byte, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	// padding
+	body = append(body, make([]byte, 16)...)
+
+	tag := []byte{'I', 'D', '3', version, 0, 0}
+	tag = append(tag, syncsafe(len(body))...)
+	return append(tag, body...)
+}
+
+func id3TextFrame(version byte, id string, encoding byte, text string) []byte {
+	data := []byte{encoding}
+	switch encoding {
+	case 1:
+		data = append(data, 0xFF, 0xFE)
+		for _, r := range text {
+			data = binary.LittleEndian.AppendUint16(data, uint16(r))
+		}
+	default:
+		data = append(data, text...)
+	}
+
+	frame := []byte(id)
+	if version == 4 {
+		frame = append(frame, syncsafe(len(data))...)
+	} else {
+		frame = binary.BigEndian.AppendUint32(frame, uint32(len(data)))
+	}
+	frame = append(frame, 0, 0)
+	return append(frame, data...)
+}
+
+func mpegFrames(size int) []byte {
+	data := make([]byte, size)
+	copy(data, []byte{0xFF, 0xFB, 0x90, 0x00})
+	return data
+}
+
+func flacFile(sampleRate, totalSamples uint64, comments ...string) []byte {
+	file := []byte("fLaC")
+
+	streamInfo := make([]byte, 34)
+	binary.BigEndian.PutUint64(streamInfo[10:18], sampleRate<<44|1<<41|15<<36|totalSamples)
+	file = append(file, 0, 0, 0, byte(len(streamInfo)))
+	file = append(file, streamInfo...)
+
+	block := binary.LittleEndian.AppendUint32(nil, 6)
+	block = append(block, "vendor"...)
+	block = binary.LittleEndian.AppendUint32(block, uint32(len(comments)))
+	for _, comment := range comments {
+		block = binary.LittleEndian.AppendUint32(block, uint32(len(comment)))
+		block = append(block, comment...)
+	}
+	file = append(file, 0x80|4, 0, byte(len(block)>>8), byte(len(block)))
+	return append(file, block...)
+}
+
+func mp4Box(typ string, content ...[]byte) []byte {
+	body := bytes.Join(content, nil)
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	b = append(b, typ...)
+	return append(b, body...)
+}
+
+func mvhd(timescale, duration uint32) []byte {
+	data := make([]byte, 100)
+	binary.BigEndian.PutUint32(data[12:16], timescale)
+	binary.BigEndian.PutUint32(data[16:20], duration)
+	return mp4Box("mvhd", data)
+}
+
+func m4aFile(timescale, duration uint32, items map[string][]byte) []byte {
+	var ilst [][]byte
+	for typ, value := range items {
+		ilst = append(ilst, mp4Box(typ, mp4Box("data", make([]byte, 8), value)))
+	}
+
+	return append(
+		mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00")),
+		mp4Box("moov",
+			mvhd(timescale, duration),
+			mp4Box("udta", mp4Box("meta", make([]byte, 4), mp4Box("hdlr", make([]byte, 25)), mp4Box("ilst", ilst...))),
+		)...,
+	)
+}
@@ -0,0 +1,186 @@
+package mediainfo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// FLAC metadata block types
+const (
+	flacStreamInfo    = 0
+	flacVorbisComment = 4
+	flacPicture       = 6
+)
+
+func readFLAC(r *io.SectionReader) (AudioTags, error) {
+	var tags AudioTags
+
+	off := int64(4) // "fLaC" marker
+	for last := false; !last; {
+		header, err := readBlock(r, off, 4)
+		if err != nil {
+			return AudioTags{}, fmt.Errorf("failed to read FLAC metadata block header: %w", err)
+		}
+
+		last = header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		off += 4
+
+		switch blockType {
+		case flacStreamInfo:
+			block, err := readBlock(r, off, size)
+			if err != nil {
+				return AudioTags{}, fmt.Errorf("failed to read FLAC STREAMINFO: %w", err)
+			}
+			tags.Duration = flacDuration(block)
+		case flacVorbisComment:
+			block, err := readBlock(r, off, size)
+			if err != nil {
+				return AudioTags{}, fmt.Errorf("failed to read FLAC VORBIS_COMMENT: %w", err)
+			}
+			applyVorbisComments(&tags, parseVorbisComments(block))
+		case flacPicture:
+			block, err := readBlock(r, off, size)
+			if err != nil {
+				return AudioTags{}, fmt.Errorf("failed to read FLAC PICTURE: %w", err)
+			}
+			picture, pictureType := flacPictureData(block)
+			if picture != nil && (tags.Cover == nil || pictureType == pictureFrontCover) {
+				tags.Cover = picture
+			}
+		}
+
+		off += size
+	}
+
+	return tags, nil
+}
+
+func flacDuration(streamInfo []byte) time.Duration {
+	if len(streamInfo) < 18 {
+		return 0
+	}
+
+	// 20 bits of sample rate, 3 bits of channels, 5 bits of bits per sample, 36 bits of total samples
+	packed := binary.BigEndian.Uint64(streamInfo[10:18])
+	sampleRate := packed >> 44
+	totalSamples := packed & (1<<36 - 1)
+	if sampleRate == 0 {
+		return 0
+	}
+
+	return time.Duration(float64(totalSamples) / float64(sampleRate) * float64(time.Second))
+}
+
+// flacPictureData returns image data and picture type of PICTURE block
+func flacPictureData(block []byte) ([]byte, uint32) {
+	if len(block) < 8 {
+		return nil, 0
+	}
+
+	pictureType := binary.BigEndian.Uint32(block)
+	off := 4
+
+	// MIME type and description
+	for i := 0; i < 2; i++ {
+		if off+4 > len(block) {
+			return nil, 0
+		}
+		off += 4 + int(binary.BigEndian.Uint32(block[off:]))
+	}
+
+	// width, height, color depth, number of colors and data length
+	off += 4 * 4
+	if off+4 > len(block) {
+		return nil, 0
+	}
+	size := int(binary.BigEndian.Uint32(block[off:]))
+	off += 4
+	if size <= 0 || off+size > len(block) {
+		return nil, 0
+	}
+
+	return block[off : off+size], pictureType
+}
+
+// parseVorbisComments parses Vorbis comment block into a map with upper-cased field names.
+// Only the first value of repeated fields is kept
+func parseVorbisComments(block []byte) map[string]string {
+	comments := make(map[string]string)
+
+	next := func(n int) ([]byte, bool) {
+		if n < 0 || n > len(block) {
+			return nil, false
+		}
+		data := block[:n]
+		block = block[n:]
+		return data, true
+	}
+	nextUint32 := func() (int, bool) {
+		data, ok := next(4)
+		if !ok {
+			return 0, false
+		}
+		return int(binary.LittleEndian.Uint32(data)), true
+	}
+
+	vendorSize, ok := nextUint32()
+	if !ok {
+		return comments
+	}
+	if _, ok := next(vendorSize); !ok {
+		return comments
+	}
+
+	count, ok := nextUint32()
+	if !ok {
+		return comments
+	}
+
+	for i := 0; i < count; i++ {
+		size, ok := nextUint32()
+		if !ok {
+			break
+		}
+		comment, ok := next(size)
+		if !ok {
+			break
+		}
+
+		name, value, found := strings.Cut(string(comment), "=")
+		if !found {
+			continue
+		}
+
+		name = strings.ToUpper(name)
+		if _, exists := comments[name]; !exists {
+			comments[name] = value
+		}
+	}
+
+	return comments
+}
+
+func applyVorbisComments(tags *AudioTags, comments map[string]string) {
+	tags.Title = comments["TITLE"]
+	tags.Album = comments["ALBUM"]
+
+	tags.Performer = comments["ARTIST"]
+	if tags.Performer == "" {
+		tags.Performer = comments["ALBUMARTIST"]
+	}
+
+	tags.Track, tags.TrackTotal = parseNumberPair(comments["TRACKNUMBER"])
+	if tags.TrackTotal == 0 {
+		tags.TrackTotal, _ = parseNumberPair(comments["TRACKTOTAL"])
+	}
+	if tags.TrackTotal == 0 {
+		tags.TrackTotal, _ = parseNumberPair(comments["TOTALTRACKS"])
+	}
+
+	tags.Disc, _ = parseNumberPair(comments["DISCNUMBER"])
+}
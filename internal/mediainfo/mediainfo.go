@@ -0,0 +1,70 @@
+// Package mediainfo reads metadata of media files (tags, duration, resolution)
+// without any external tools or cgo dependencies
+package mediainfo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrUnsupported is returned when the file format is not recognized
+	ErrUnsupported = errors.New("unsupported format")
+	// ErrInvalid is returned when the file format is recognized, but the file is malformed
+	ErrInvalid = errors.New("invalid file")
+)
+
+// maxBlockSize limits a size of a single metadata block read into memory
+const maxBlockSize = 64 << 20
+
+// readerAt adapts io.ReadSeeker to io.ReaderAt.
+// It is not safe for concurrent use
+type readerAt struct {
+	rs io.ReadSeeker
+}
+
+func (r readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := r.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return io.ReadFull(r.rs, p)
+}
+
+// sectionOf returns a section of the whole r and restores position of r afterwards
+func sectionOf(r io.ReadSeeker) (section *io.SectionReader, restore func() error, err error) {
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, fmt.Errorf("r.Seek(0, io.SeekCurrent): %w", err)
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("r.Seek(0, io.SeekEnd): %w", err)
+	}
+
+	restore = func() error {
+		_, err := r.Seek(pos, io.SeekStart)
+		return err
+	}
+
+	return io.NewSectionReader(readerAt{rs: r}, 0, size), restore, nil
+}
+
+// readBlock reads size bytes located at off
+func readBlock(r io.ReaderAt, off, size int64) ([]byte, error) {
+	if size < 0 || size > maxBlockSize {
+		return nil, fmt.Errorf("%w: block of %d bytes at %d", ErrInvalid, size, off)
+	}
+
+	buf := make([]byte, size)
+	if _, err := r.ReadAt(buf, off); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: unexpected end of file at %d", ErrInvalid, off)
+		}
+		return nil, err
+	}
+
+	return buf, nil
+}
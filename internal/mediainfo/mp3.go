@@ -0,0 +1,462 @@
+package mediainfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+const id3HeaderSize = 10
+
+// id3Frame is a raw ID3v2 frame
+type id3Frame struct {
+	id   string
+	data []byte
+}
+
+func readMP3(r *io.SectionReader) (AudioTags, error) {
+	var (
+		tags       AudioTags
+		audioStart int64
+	)
+
+	header := make([]byte, id3HeaderSize)
+	if _, err := r.ReadAt(header, 0); err == nil && bytes.HasPrefix(header, []byte("ID3")) {
+		frames, tagSize, err := readID3Frames(r, header)
+		if err != nil {
+			return AudioTags{}, fmt.Errorf("readID3Frames(): %w", err)
+		}
+
+		tags = id3Tags(frames)
+		audioStart = tagSize
+	}
+
+	if tags.Duration == 0 {
+		duration, err := mpegDuration(r, audioStart)
+		if err == nil {
+			tags.Duration = duration
+		}
+	}
+
+	return tags, nil
+}
+
+// readID3Frames reads frames of ID3v2.2, ID3v2.3 or ID3v2.4 tag and returns them with the total tag size
+func readID3Frames(r io.ReaderAt, header []byte) ([]id3Frame, int64, error) {
+	version := header[3]
+	flags := header[5]
+	size := int64(syncsafe(header[6:10]))
+
+	tagSize := id3HeaderSize + size
+	if flags&0x10 != 0 {
+		// footer present
+		tagSize += id3HeaderSize
+	}
+
+	if version < 2 || version > 4 {
+		return nil, tagSize, fmt.Errorf("%w: ID3v2.%d", ErrUnsupported, version)
+	}
+
+	body, err := readBlock(r, id3HeaderSize, size)
+	if err != nil {
+		return nil, tagSize, err
+	}
+
+	if version < 4 && flags&0x80 != 0 {
+		body = removeUnsync(body)
+	}
+
+	if flags&0x40 != 0 && version > 2 {
+		// skip extended header
+		if len(body) < 4 {
+			return nil, tagSize, fmt.Errorf("%w: truncated ID3 extended header", ErrInvalid)
+		}
+		extSize := int(binary.BigEndian.Uint32(body[:4]))
+		if version == 4 {
+			extSize = int(syncsafe(body[:4]))
+		} else {
+			// in ID3v2.3 size excludes itself
+			extSize += 4
+		}
+		if extSize > len(body) {
+			return nil, tagSize, fmt.Errorf("%w: ID3 extended header of %d bytes", ErrInvalid, extSize)
+		}
+		body = body[extSize:]
+	}
+
+	idSize, headerSize := 4, 10
+	if version == 2 {
+		idSize, headerSize = 3, 6
+	}
+
+	var frames []id3Frame
+	for len(body) >= headerSize {
+		id := string(body[:idSize])
+		if body[0] == 0 {
+			// padding
+			break
+		}
+
+		var frameSize int
+		switch version {
+		case 2:
+			frameSize = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(body[4:8]))
+		case 4:
+			frameSize = int(syncsafe(body[4:8]))
+		}
+
+		if frameSize < 0 || headerSize+frameSize > len(body) {
+			return nil, tagSize, fmt.Errorf("%w: ID3 frame %q of %d bytes", ErrInvalid, id, frameSize)
+		}
+
+		data := body[headerSize : headerSize+frameSize]
+		if version > 2 {
+			data = decodeID3FrameFlags(version, body[8:10], data)
+		}
+		if data != nil {
+			frames = append(frames, id3Frame{id: id, data: data})
+		}
+
+		body = body[headerSize+frameSize:]
+	}
+
+	return frames, tagSize, nil
+}
+
+// decodeID3FrameFlags applies frame format flags to data.
+// nil is returned if the frame can't be decoded
+func decodeID3FrameFlags(version byte, flags []byte, data []byte) []byte {
+	format := flags[1]
+
+	if version == 3 {
+		// compressed or encrypted
+		if format&0xC0 != 0 {
+			return nil
+		}
+		return data
+	}
+
+	// compressed or encrypted
+	if format&0x0C != 0 {
+		return nil
+	}
+	if format&0x01 != 0 {
+		// data length indicator
+		if len(data) < 4 {
+			return nil
+		}
+		data = data[4:]
+	}
+	if format&0x02 != 0 {
+		data = removeUnsync(data)
+	}
+
+	return data
+}
+
+func id3Tags(frames []id3Frame) AudioTags {
+	var (
+		tags        AudioTags
+		albumArtist string
+	)
+
+	for _, frame := range frames {
+		switch frame.id {
+		case "TIT2", "TT2":
+			tags.Title = id3Text(frame.data)
+		case "TPE1", "TP1":
+			tags.Performer = id3Text(frame.data)
+		case "TPE2", "TP2":
+			albumArtist = id3Text(frame.data)
+		case "TALB", "TAL":
+			tags.Album = id3Text(frame.data)
+		case "TRCK", "TRK":
+			tags.Track, tags.TrackTotal = parseNumberPair(id3Text(frame.data))
+		case "TPOS", "TPA":
+			tags.Disc, _ = parseNumberPair(id3Text(frame.data))
+		case "APIC", "PIC":
+			picture, pictureType := id3Picture(frame.id, frame.data)
+			if picture != nil && (tags.Cover == nil || pictureType == pictureFrontCover) {
+				tags.Cover = picture
+			}
+		case "TLEN", "TLE":
+			if ms, err := strconv.ParseInt(id3Text(frame.data), 10, 64); err == nil && ms > 0 {
+				tags.Duration = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	if tags.Performer == "" {
+		tags.Performer = albumArtist
+	}
+
+	return tags
+}
+
+// pictureFrontCover is a picture type of the front cover in ID3 and FLAC
+const pictureFrontCover = 3
+
+// id3Picture returns image data and picture type of APIC (ID3v2.3, ID3v2.4) or PIC (ID3v2.2) frame
+func id3Picture(id string, data []byte) ([]byte, byte) {
+	if len(data) < 2 {
+		return nil, 0
+	}
+
+	encoding, rest := data[0], data[1:]
+	if id == "PIC" {
+		// 3 bytes of image format
+		if len(rest) < 3 {
+			return nil, 0
+		}
+		rest = rest[3:]
+	} else {
+		// null-terminated MIME type
+		_, rest = splitID3String(0, rest)
+	}
+
+	if len(rest) < 1 {
+		return nil, 0
+	}
+	pictureType := rest[0]
+
+	// description
+	_, rest = splitID3String(encoding, rest[1:])
+	if len(rest) == 0 {
+		return nil, 0
+	}
+
+	return rest, pictureType
+}
+
+// id3Text decodes text frame. Only the first value of multi-value frames is returned
+func id3Text(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	return decodeID3String(data[0], data[1:])
+}
+
+// decodeID3String decodes first null-terminated string of the encoding
+func decodeID3String(encoding byte, data []byte) string {
+	text, _ := splitID3String(encoding, data)
+	return text
+}
+
+// splitID3String decodes first null-terminated string of the encoding
+// and returns it with the rest of data
+func splitID3String(encoding byte, data []byte) (text string, rest []byte) {
+	switch encoding {
+	case 1, 2:
+		end := len(data) &^ 1
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				end, rest = i, data[i+2:]
+				break
+			}
+		}
+		return decodeUTF16(data[:end], encoding == 2), rest
+	default:
+		end := len(data)
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			end, rest = i, data[i+1:]
+		}
+		if encoding == 3 {
+			return string(data[:end]), rest
+		}
+		return decodeLatin1(data[:end]), rest
+	}
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+
+	if len(data) >= 2 {
+		switch {
+		case data[0] == 0xFF && data[1] == 0xFE:
+			order, data = binary.LittleEndian, data[2:]
+		case data[0] == 0xFE && data[1] == 0xFF:
+			order, data = binary.BigEndian, data[2:]
+		}
+	}
+
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, order.Uint16(data[i:]))
+	}
+
+	return string(utf16.Decode(units))
+}
+
+func decodeLatin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+
+	return string(runes)
+}
+
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7F)<<21 | uint32(b[1]&0x7F)<<14 | uint32(b[2]&0x7F)<<7 | uint32(b[3]&0x7F)
+}
+
+// removeUnsync reverts ID3 unsynchronisation scheme: 0xFF 0x00 is replaced with 0xFF
+func removeUnsync(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{0xFF, 0x00}, []byte{0xFF})
+}
+
+// MPEG audio frame header tables indexed by [version][layer]
+var (
+	// kbit/s; version: 0 - MPEG1, 1 - MPEG2 and MPEG2.5; layer: 0 - Layer I, 1 - Layer II, 2 - Layer III
+	mpegBitrates = [2][3][16]int{
+		{
+			{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+			{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+		},
+		{
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		},
+	}
+	mpegSamplesPerFrame = [2][3]int{
+		{384, 1152, 1152},
+		{384, 1152, 576},
+	}
+	mpeg1SampleRates = [3]int{44100, 48000, 32000}
+)
+
+// mpegFrame is a parsed MPEG audio frame header
+type mpegFrame struct {
+	mpeg1      bool
+	layer      int // 0 - Layer I, 1 - Layer II, 2 - Layer III
+	bitrate    int // bit/s
+	sampleRate int
+	mono       bool
+}
+
+func isFrameSync(b []byte) bool {
+	return len(b) >= 2 && b[0] == 0xFF && b[1]&0xE0 == 0xE0
+}
+
+func parseMPEGFrame(b []byte) (mpegFrame, bool) {
+	if len(b) < 4 || !isFrameSync(b) {
+		return mpegFrame{}, false
+	}
+
+	versionBits := b[1] >> 3 & 0x03
+	layerBits := b[1] >> 1 & 0x03
+	bitrateIndex := b[2] >> 4
+	sampleRateIndex := b[2] >> 2 & 0x03
+
+	if versionBits == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return mpegFrame{}, false
+	}
+
+	frame := mpegFrame{
+		mpeg1: versionBits == 3,
+		layer: 3 - int(layerBits),
+		mono:  b[3]>>6 == 3,
+	}
+
+	version := 1
+	if frame.mpeg1 {
+		version = 0
+	}
+
+	frame.bitrate = mpegBitrates[version][frame.layer][bitrateIndex] * 1000
+	frame.sampleRate = mpeg1SampleRates[sampleRateIndex]
+	switch versionBits {
+	case 2: // MPEG2
+		frame.sampleRate /= 2
+	case 0: // MPEG2.5
+		frame.sampleRate /= 4
+	}
+
+	return frame, true
+}
+
+func (f mpegFrame) samplesPerFrame() int {
+	version := 1
+	if f.mpeg1 {
+		version = 0
+	}
+
+	return mpegSamplesPerFrame[version][f.layer]
+}
+
+// xingOffset returns offset of Xing/Info header from the start of the frame.
+// The header is located right after the frame side information
+func (f mpegFrame) xingOffset() int {
+	switch {
+	case f.mpeg1 && f.mono:
+		return 4 + 17
+	case f.mpeg1:
+		return 4 + 32
+	case f.mono:
+		return 4 + 9
+	default:
+		return 4 + 17
+	}
+}
+
+// maxFrameSearch limits how far from the tag end the first frame is searched
+const maxFrameSearch = 64 << 10
+
+// mpegDuration finds the first MPEG audio frame after start and computes duration
+// using Xing/Info or VBRI header, or assuming constant bitrate if there are none
+func mpegDuration(r *io.SectionReader, start int64) (time.Duration, error) {
+	buf := make([]byte, maxFrameSearch)
+	n, err := r.ReadAt(buf, start)
+	if n == 0 && err != nil {
+		return 0, fmt.Errorf("r.ReadAt(buf, %d): %w", start, err)
+	}
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		frame, ok := parseMPEGFrame(buf[i:])
+		if !ok {
+			continue
+		}
+
+		if frames, ok := vbrFrames(buf[i:], frame); ok {
+			seconds := float64(frames) * float64(frame.samplesPerFrame()) / float64(frame.sampleRate)
+			return time.Duration(seconds * float64(time.Second)), nil
+		}
+
+		audioSize := r.Size() - start - int64(i)
+		return time.Duration(float64(audioSize) * 8 / float64(frame.bitrate) * float64(time.Second)), nil
+	}
+
+	return 0, fmt.Errorf("%w: MPEG audio frame not found", ErrInvalid)
+}
+
+// vbrFrames returns the number of frames stored in Xing/Info or VBRI header of the frame
+func vbrFrames(frameData []byte, frame mpegFrame) (uint32, bool) {
+	if off := frame.xingOffset(); len(frameData) >= off+12 {
+		tag := string(frameData[off : off+4])
+		flags := binary.BigEndian.Uint32(frameData[off+4:])
+		if (tag == "Xing" || tag == "Info") && flags&0x01 != 0 {
+			return binary.BigEndian.Uint32(frameData[off+8:]), true
+		}
+	}
+
+	// VBRI header is located 32 bytes after the frame header
+	const vbriOffset = 4 + 32
+	if len(frameData) >= vbriOffset+18 && string(frameData[vbriOffset:vbriOffset+4]) == "VBRI" {
+		return binary.BigEndian.Uint32(frameData[vbriOffset+14:]), true
+	}
+
+	return 0, false
+}
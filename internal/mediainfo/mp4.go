@@ -0,0 +1,241 @@
+package mediainfo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// box is an ISO base media file format box (QuickTime atom)
+type box struct {
+	typ string
+	// offset of the box content, excluding header
+	offset int64
+	// size of the box content, excluding header
+	size int64
+}
+
+// errStopWalk stops walkBoxes without an error
+var errStopWalk = errors.New("stop walk")
+
+// walkBoxes calls fn for every box located in [start, end) of r
+func walkBoxes(r io.ReaderAt, start, end int64, fn func(b box) error) error {
+	header := make([]byte, 16)
+
+	for off := start; off+8 <= end; {
+		if _, err := r.ReadAt(header[:8], off); err != nil {
+			return fmt.Errorf("%w: failed to read box header at %d", ErrInvalid, off)
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		b := box{
+			typ:    string(header[4:8]),
+			offset: off + 8,
+		}
+
+		switch size {
+		case 0:
+			// box extends to the end of file
+			size = end - off
+		case 1:
+			if _, err := r.ReadAt(header[8:16], off+8); err != nil {
+				return fmt.Errorf("%w: failed to read box %q size at %d", ErrInvalid, b.typ, off)
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			b.offset += 8
+		}
+
+		if size < b.offset-off || off+size > end {
+			return fmt.Errorf("%w: box %q of %d bytes at %d", ErrInvalid, b.typ, size, off)
+		}
+		b.size = off + size - b.offset
+
+		if err := fn(b); err != nil {
+			if errors.Is(err, errStopWalk) {
+				return nil
+			}
+			return err
+		}
+
+		off += size
+	}
+
+	return nil
+}
+
+// findBox returns the first box found by the path of box types, e.g. "moov", "udta", "meta"
+func findBox(r io.ReaderAt, parent box, path ...string) (box, bool, error) {
+	current := parent
+	for _, typ := range path {
+		var (
+			found bool
+			child box
+		)
+
+		start := current.offset
+		if current.typ == "meta" {
+			start += metaHeaderSize(r, current)
+		}
+
+		err := walkBoxes(r, start, current.offset+current.size, func(b box) error {
+			if b.typ == typ {
+				found, child = true, b
+				return errStopWalk
+			}
+			return nil
+		})
+		if err != nil || !found {
+			return box{}, false, err
+		}
+
+		current = child
+	}
+
+	return current, true, nil
+}
+
+// metaHeaderSize returns size of version and flags of "meta" box.
+// In ISO files "meta" is a full box, but in QuickTime files it is not
+func metaHeaderSize(r io.ReaderAt, meta box) int64 {
+	buf := make([]byte, 8)
+	if _, err := r.ReadAt(buf, meta.offset); err != nil {
+		return 4
+	}
+
+	if string(buf[4:8]) == "hdlr" {
+		return 0
+	}
+	return 4
+}
+
+// fileBox returns a pseudo box spanning the whole r
+func fileBox(r *io.SectionReader) box {
+	return box{typ: "", offset: 0, size: r.Size()}
+}
+
+// mvhdDuration reads movie duration from "mvhd" box
+func mvhdDuration(r io.ReaderAt, mvhd box) (time.Duration, error) {
+	data, err := readBlock(r, mvhd.offset, min(mvhd.size, 32))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read mvhd: %w", err)
+	}
+
+	var timescale, duration uint64
+	switch {
+	case len(data) >= 32 && data[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(data[20:24]))
+		duration = binary.BigEndian.Uint64(data[24:32])
+	case len(data) >= 20 && data[0] == 0:
+		timescale = uint64(binary.BigEndian.Uint32(data[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+	default:
+		return 0, fmt.Errorf("%w: mvhd of %d bytes", ErrInvalid, len(data))
+	}
+
+	if timescale == 0 {
+		return 0, nil
+	}
+
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), nil
+}
+
+func readM4A(r *io.SectionReader) (AudioTags, error) {
+	var tags AudioTags
+
+	moov, found, err := findBox(r, fileBox(r), "moov")
+	if err != nil {
+		return AudioTags{}, fmt.Errorf("findBox(moov): %w", err)
+	}
+	if !found {
+		return AudioTags{}, fmt.Errorf("%w: moov box not found", ErrInvalid)
+	}
+
+	if mvhd, found, err := findBox(r, moov, "mvhd"); err == nil && found {
+		if tags.Duration, err = mvhdDuration(r, mvhd); err != nil {
+			return AudioTags{}, fmt.Errorf("mvhdDuration(): %w", err)
+		}
+	}
+
+	ilst, found, err := findBox(r, moov, "udta", "meta", "ilst")
+	if err != nil {
+		return AudioTags{}, fmt.Errorf("findBox(udta/meta/ilst): %w", err)
+	}
+	if !found {
+		return tags, nil
+	}
+
+	var albumArtist string
+	err = walkBoxes(r, ilst.offset, ilst.offset+ilst.size, func(item box) error {
+		data, err := ilstItemData(r, item)
+		if err != nil || data == nil {
+			return err
+		}
+
+		switch item.typ {
+		case "\xa9nam":
+			tags.Title = string(data)
+		case "\xa9ART":
+			tags.Performer = string(data)
+		case "aART":
+			albumArtist = string(data)
+		case "\xa9alb":
+			tags.Album = string(data)
+		case "trkn":
+			tags.Track, tags.TrackTotal = ilstNumberPair(data)
+		case "disk":
+			tags.Disc, _ = ilstNumberPair(data)
+		case "covr":
+			tags.Cover = data
+		}
+
+		return nil
+	})
+	if err != nil {
+		return AudioTags{}, fmt.Errorf("failed to read ilst: %w", err)
+	}
+
+	if tags.Performer == "" {
+		tags.Performer = albumArtist
+	}
+
+	return tags, nil
+}
+
+// mp4Cover returns cover art stored in "covr" item of iTunes metadata.
+// nil is returned if there is none
+func mp4Cover(r io.ReaderAt, moov box) ([]byte, error) {
+	covr, found, err := findBox(r, moov, "udta", "meta", "ilst", "covr")
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return ilstItemData(r, covr)
+}
+
+// ilstItemData returns value of the "data" box of the ilst item.
+// nil is returned if there is no such box
+func ilstItemData(r io.ReaderAt, item box) ([]byte, error) {
+	data, found, err := findBox(r, item, "data")
+	if err != nil || !found {
+		return nil, err
+	}
+
+	// skip type indicator and locale
+	const dataHeaderSize = 8
+	if data.size < dataHeaderSize {
+		return nil, fmt.Errorf("%w: data box of %d bytes", ErrInvalid, data.size)
+	}
+
+	return readBlock(r, data.offset+dataHeaderSize, data.size-dataHeaderSize)
+}
+
+// ilstNumberPair parses "trkn" and "disk" values: 2 reserved bytes, number and total
+func ilstNumberPair(data []byte) (number, total int) {
+	if len(data) < 6 {
+		return 0, 0
+	}
+
+	return int(binary.BigEndian.Uint16(data[2:4])), int(binary.BigEndian.Uint16(data[4:6]))
+}
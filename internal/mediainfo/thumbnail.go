@@ -0,0 +1,149 @@
+package mediainfo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	// registered decoders of supported image formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Telegram limits for thumbnails of documents
+const (
+	ThumbnailMaxSide = 320
+	ThumbnailMaxSize = 200 << 10
+)
+
+// ThumbnailMaxPixels limits a number of pixels of images Thumbnail decodes,
+// a decoded image takes up to 4 bytes per pixel
+const ThumbnailMaxPixels = 5000 * 5000
+
+// ErrTooLarge is returned when the image has more than ThumbnailMaxPixels pixels
+var ErrTooLarge = errors.New("image is too large")
+
+// ImageConfig is a size and format of an image
+type ImageConfig struct {
+	Width  int
+	Height int
+	// Format is a name of the image format: "jpeg", "png" or "gif"
+	Format string
+}
+
+// ReadImageConfig reads image dimensions and format without decoding the whole image
+func ReadImageConfig(r io.Reader) (ImageConfig, error) {
+	config, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("%w: %s", ErrUnsupported, err)
+	}
+
+	return ImageConfig{
+		Width:  config.Width,
+		Height: config.Height,
+		Format: format,
+	}, nil
+}
+
+// Thumbnail decodes JPEG, PNG or GIF image and returns JPEG thumbnail of it
+// fitting Telegram limits: sides are at most ThumbnailMaxSide and size is at most ThumbnailMaxSize.
+// Images having more than ThumbnailMaxPixels pixels are not decoded, ErrTooLarge is returned
+func Thumbnail(r io.Reader) ([]byte, error) {
+	// the header read by DecodeConfig is decoded again with the rest of the image
+	var header bytes.Buffer
+	config, err := ReadImageConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(config.Width)*int64(config.Height) > ThumbnailMaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, err)
+	}
+
+	thumb := scaleDown(src, ThumbnailMaxSide)
+
+	var buf bytes.Buffer
+	for quality := 87; quality > 0; quality -= 20 {
+		buf.Reset()
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, fmt.Errorf("jpeg.Encode(): %w", err)
+		}
+
+		if buf.Len() <= ThumbnailMaxSize {
+			return buf.Bytes(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("thumbnail exceeds %d bytes", ThumbnailMaxSize)
+}
+
+// scaleDown fits the image into maxSide x maxSide square keeping aspect ratio.
+// Each pixel of the result is an average of the source pixels it covers
+func scaleDown(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := srcW, srcH
+	if srcW > maxSide || srcH > maxSide {
+		if srcW >= srcH {
+			dstW, dstH = maxSide, max(1, srcH*maxSide/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*maxSide/srcH), maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/dstW)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return flatten(dst)
+}
+
+// flatten draws the image over white background since JPEG has no alpha channel
+func flatten(img *image.RGBA) image.Image {
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		alpha := uint32(img.Pix[i+3])
+		if alpha == 0xFF {
+			continue
+		}
+
+		// colors are alpha-premultiplied
+		for c := 0; c < 3; c++ {
+			img.Pix[i+c] = uint8(uint32(img.Pix[i+c]) + 0xFF - alpha)
+		}
+		img.Pix[i+3] = 0xFF
+	}
+
+	return img
+}
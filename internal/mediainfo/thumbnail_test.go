@@ -0,0 +1,69 @@
+package mediainfo_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/aleksander-git/telegram-torrent/internal/mediainfo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThumbnail(t *testing.T) {
+	var tests = []struct {
+		name                  string
+		width, height         int
+		wantWidth, wantHeight int
+	}{
+		{name: "landscape", width: 1000, height: 500, wantWidth: 320, wantHeight: 160},
+		{name: "portrait", width: 600, height: 1200, wantWidth: 160, wantHeight: 320},
+		{name: "small", width: 100, height: 80, wantWidth: 100, wantHeight: 80},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, test.width, test.height))
+			for y := 0; y < test.height; y++ {
+				for x := 0; x < test.width; x++ {
+					img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: uint8(x + y)})
+				}
+			}
+
+			var buf bytes.Buffer
+			require.NoError(t, png.Encode(&buf, img))
+
+			config, err := mediainfo.ReadImageConfig(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, mediainfo.ImageConfig{Width: test.width, Height: test.height, Format: "png"}, config)
+
+			thumb, err := mediainfo.Thumbnail(&buf)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(thumb), mediainfo.ThumbnailMaxSize)
+
+			decoded, err := jpeg.Decode(bytes.NewReader(thumb))
+			require.NoError(t, err)
+			require.Equal(t, test.wantWidth, decoded.Bounds().Dx())
+			require.Equal(t, test.wantHeight, decoded.Bounds().Dy())
+		})
+	}
+}
+
+func TestThumbnail_TooLarge(t *testing.T) {
+	// only the header is encoded, the image must not be decoded
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))))
+
+	header := buf.Bytes()
+	// IHDR chunk follows 8 bytes of the signature, 4 bytes of length and 4 bytes of type
+	binary.BigEndian.PutUint32(header[16:], 20000)
+	binary.BigEndian.PutUint32(header[20:], 20000)
+	binary.BigEndian.PutUint32(header[29:], crc32.ChecksumIEEE(header[12:29]))
+
+	_, err := mediainfo.Thumbnail(bytes.NewReader(header))
+	require.ErrorIs(t, err, mediainfo.ErrTooLarge)
+}
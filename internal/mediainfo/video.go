@@ -0,0 +1,443 @@
+package mediainfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// VideoInfo is a metadata of a video file
+type VideoInfo struct {
+	Width    int
+	Height   int
+	Duration time.Duration
+
+	// Streamable reports whether the video can be played before it is fully downloaded.
+	// For MP4 it means that "moov" box is located before the media data
+	Streamable bool
+
+	// Cover is an embedded cover art image, nil if there is none
+	Cover []byte
+}
+
+// ProbeVideo reads dimensions and duration of MP4 (QuickTime) or Matroska (WebM) file.
+// The format is detected by the content, not by the file extension.
+//
+// Position of r is restored after reading
+func ProbeVideo(r io.ReadSeeker) (info VideoInfo, err error) {
+	section, restore, err := sectionOf(r)
+	if err != nil {
+		return VideoInfo{}, err
+	}
+	defer func() {
+		if restoreErr := restore(); restoreErr != nil && err == nil {
+			err = fmt.Errorf("failed to restore reader position: %w", restoreErr)
+		}
+	}()
+
+	head := make([]byte, 12)
+	if _, err := section.ReadAt(head, 0); err != nil {
+		return VideoInfo{}, fmt.Errorf("%w: file is too short", ErrUnsupported)
+	}
+
+	switch {
+	case bytes.Equal(head[4:8], []byte("ftyp")):
+		return probeMP4(section)
+	case bytes.HasPrefix(head, ebmlMagic):
+		return probeMatroska(section)
+	default:
+		return VideoInfo{}, ErrUnsupported
+	}
+}
+
+func probeMP4(r *io.SectionReader) (VideoInfo, error) {
+	var (
+		info                 VideoInfo
+		moov                 box
+		moovFound, mdatFound bool
+	)
+
+	// top-level boxes order tells whether the file is streamable
+	err := walkBoxes(r, 0, r.Size(), func(b box) error {
+		switch b.typ {
+		case "moov":
+			moov, moovFound = b, true
+			if !mdatFound {
+				info.Streamable = true
+			}
+		case "mdat":
+			mdatFound = true
+		}
+		return nil
+	})
+	if err != nil {
+		return VideoInfo{}, fmt.Errorf("walkBoxes(): %w", err)
+	}
+	if !moovFound {
+		return VideoInfo{}, fmt.Errorf("%w: moov box not found", ErrInvalid)
+	}
+
+	if mvhd, found, err := findBox(r, moov, "mvhd"); err == nil && found {
+		if info.Duration, err = mvhdDuration(r, mvhd); err != nil {
+			return VideoInfo{}, fmt.Errorf("mvhdDuration(): %w", err)
+		}
+	}
+
+	if info.Cover, err = mp4Cover(r, moov); err != nil {
+		return VideoInfo{}, fmt.Errorf("mp4Cover(): %w", err)
+	}
+
+	err = walkBoxes(r, moov.offset, moov.offset+moov.size, func(trak box) error {
+		if trak.typ != "trak" {
+			return nil
+		}
+
+		handler, err := trackHandler(r, trak)
+		if err != nil || handler != "vide" {
+			return err
+		}
+
+		tkhd, found, err := findBox(r, trak, "tkhd")
+		if err != nil || !found {
+			return err
+		}
+
+		info.Width, info.Height, err = tkhdDimensions(r, tkhd)
+		if err != nil {
+			return fmt.Errorf("tkhdDimensions(): %w", err)
+		}
+
+		return errStopWalk
+	})
+	if err != nil {
+		return VideoInfo{}, fmt.Errorf("failed to read tracks: %w", err)
+	}
+
+	return info, nil
+}
+
+// trackHandler returns handler type of the track, e.g. "vide" or "soun"
+func trackHandler(r io.ReaderAt, trak box) (string, error) {
+	hdlr, found, err := findBox(r, trak, "mdia", "hdlr")
+	if err != nil || !found {
+		return "", err
+	}
+
+	// version and flags, pre_defined, handler_type
+	data, err := readBlock(r, hdlr.offset, min(hdlr.size, 12))
+	if err != nil {
+		return "", fmt.Errorf("failed to read hdlr: %w", err)
+	}
+	if len(data) < 12 {
+		return "", fmt.Errorf("%w: hdlr of %d bytes", ErrInvalid, len(data))
+	}
+
+	return string(data[8:12]), nil
+}
+
+// tkhdDimensions reads width and height of the track taking rotation into account
+func tkhdDimensions(r io.ReaderAt, tkhd box) (width, height int, err error) {
+	data, err := readBlock(r, tkhd.offset, min(tkhd.size, 96))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read tkhd: %w", err)
+	}
+
+	// offset of transformation matrix
+	matrix := 40
+	if len(data) > 0 && data[0] == 1 {
+		matrix = 52
+	}
+	if len(data) < matrix+36+8 {
+		return 0, 0, fmt.Errorf("%w: tkhd of %d bytes", ErrInvalid, len(data))
+	}
+
+	// 16.16 fixed-point numbers
+	width = int(binary.BigEndian.Uint32(data[matrix+36:]) >> 16)
+	height = int(binary.BigEndian.Uint32(data[matrix+40:]) >> 16)
+
+	// video rotated by 90 or 270 degrees has zero "a" element of the matrix
+	if binary.BigEndian.Uint32(data[matrix:]) == 0 {
+		width, height = height, width
+	}
+
+	return width, height, nil
+}
+
+// Matroska element IDs
+const (
+	ebmlIDSegment       = 0x18538067
+	ebmlIDInfo          = 0x1549A966
+	ebmlIDTimecodeScale = 0x2AD7B1
+	ebmlIDDuration      = 0x4489
+	ebmlIDTracks        = 0x1654AE6B
+	ebmlIDTrackEntry    = 0xAE
+	ebmlIDTrackType     = 0x83
+	ebmlIDVideo         = 0xE0
+	ebmlIDPixelWidth    = 0xB0
+	ebmlIDPixelHeight   = 0xBA
+	ebmlIDCluster       = 0x1F43B675
+	ebmlIDAttachments   = 0x1941A469
+	ebmlIDAttachedFile  = 0x61A7
+	ebmlIDFileName      = 0x466E
+	ebmlIDFileMimeType  = 0x4660
+	ebmlIDFileData      = 0x465C
+
+	matroskaTrackTypeVideo = 1
+)
+
+var ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+// ebmlElement is an element of EBML document
+type ebmlElement struct {
+	id     uint32
+	offset int64
+	size   int64
+}
+
+// readEBMLVarint reads variable size integer at off and returns it with its length.
+// If keepMarker is false, the length marker bit is cleared as required for element sizes
+func readEBMLVarint(r io.ReaderAt, off int64, keepMarker bool) (value uint64, length int, err error) {
+	buf := make([]byte, 8)
+	if _, err := r.ReadAt(buf[:1], off); err != nil {
+		return 0, 0, fmt.Errorf("%w: failed to read EBML varint at %d", ErrInvalid, off)
+	}
+
+	for length = 1; length <= 8; length++ {
+		if buf[0]&(0x80>>(length-1)) != 0 {
+			break
+		}
+	}
+	if length > 8 {
+		return 0, 0, fmt.Errorf("%w: EBML varint at %d", ErrInvalid, off)
+	}
+
+	if length > 1 {
+		if _, err := r.ReadAt(buf[1:length], off+1); err != nil {
+			return 0, 0, fmt.Errorf("%w: failed to read EBML varint at %d", ErrInvalid, off)
+		}
+	}
+
+	if !keepMarker {
+		buf[0] &^= 0x80 >> (length - 1)
+	}
+	for _, b := range buf[:length] {
+		value = value<<8 | uint64(b)
+	}
+
+	return value, length, nil
+}
+
+// walkEBML calls fn for every element located in [start, end) of r.
+// Elements of unknown size are extended up to the end
+func walkEBML(r io.ReaderAt, start, end int64, fn func(e ebmlElement) error) error {
+	for off := start; off < end; {
+		id, idLength, err := readEBMLVarint(r, off, true)
+		if err != nil {
+			return err
+		}
+
+		size, sizeLength, err := readEBMLVarint(r, off+int64(idLength), false)
+		if err != nil {
+			return err
+		}
+
+		e := ebmlElement{
+			id:     uint32(id),
+			offset: off + int64(idLength+sizeLength),
+			size:   int64(size),
+		}
+		if size == 1<<(7*sizeLength)-1 || e.offset+e.size > end {
+			// unknown or truncated size
+			e.size = end - e.offset
+		}
+
+		if err := fn(e); err != nil {
+			if errors.Is(err, errStopWalk) {
+				return nil
+			}
+			return err
+		}
+
+		off = e.offset + e.size
+	}
+
+	return nil
+}
+
+func readEBMLUint(r io.ReaderAt, e ebmlElement) (uint64, error) {
+	data, err := readBlock(r, e.offset, min(e.size, 8))
+	if err != nil {
+		return 0, err
+	}
+
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+
+	return value, nil
+}
+
+func readEBMLFloat(r io.ReaderAt, e ebmlElement) (float64, error) {
+	data, err := readBlock(r, e.offset, e.size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	default:
+		return 0, fmt.Errorf("%w: EBML float of %d bytes", ErrInvalid, len(data))
+	}
+}
+
+// probeMatroska reads Info and Tracks elements of the first segment.
+//
+// Telegram clients stream MP4 files only, so Matroska files are never reported as streamable
+func probeMatroska(r *io.SectionReader) (VideoInfo, error) {
+	var (
+		info          VideoInfo
+		timecodeScale uint64 = 1000000
+		duration      float64
+	)
+
+	err := walkEBML(r, 0, r.Size(), func(segment ebmlElement) error {
+		if segment.id != ebmlIDSegment {
+			return nil
+		}
+
+		var infoFound, tracksFound bool
+		err := walkEBML(r, segment.offset, segment.offset+segment.size, func(e ebmlElement) error {
+			switch e.id {
+			case ebmlIDInfo:
+				infoFound = true
+				return walkEBML(r, e.offset, e.offset+e.size, func(e ebmlElement) (err error) {
+					switch e.id {
+					case ebmlIDTimecodeScale:
+						timecodeScale, err = readEBMLUint(r, e)
+					case ebmlIDDuration:
+						duration, err = readEBMLFloat(r, e)
+					}
+					return err
+				})
+			case ebmlIDTracks:
+				tracksFound = true
+				return walkEBML(r, e.offset, e.offset+e.size, func(e ebmlElement) error {
+					if e.id != ebmlIDTrackEntry {
+						return nil
+					}
+					return readMatroskaTrack(r, e, &info)
+				})
+			case ebmlIDAttachments:
+				return walkEBML(r, e.offset, e.offset+e.size, func(e ebmlElement) error {
+					if e.id != ebmlIDAttachedFile {
+						return nil
+					}
+					return readMatroskaCover(r, e, &info)
+				})
+			case ebmlIDCluster:
+				if infoFound && tracksFound {
+					return errStopWalk
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return errStopWalk
+	})
+	if err != nil {
+		return VideoInfo{}, fmt.Errorf("failed to read Matroska segment: %w", err)
+	}
+
+	info.Duration = time.Duration(duration * float64(timecodeScale))
+
+	return info, nil
+}
+
+// readMatroskaCover sets the attached image as a cover of the info.
+// Images named "cover.*" take precedence over other ones
+func readMatroskaCover(r io.ReaderAt, attachment ebmlElement, info *VideoInfo) error {
+	var (
+		name, mimeType string
+		data           ebmlElement
+	)
+
+	err := walkEBML(r, attachment.offset, attachment.offset+attachment.size, func(e ebmlElement) error {
+		switch e.id {
+		case ebmlIDFileName, ebmlIDFileMimeType:
+			value, err := readBlock(r, e.offset, e.size)
+			if err != nil {
+				return err
+			}
+			if e.id == ebmlIDFileName {
+				name = string(value)
+			} else {
+				mimeType = string(value)
+			}
+		case ebmlIDFileData:
+			data = e
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	isCover := strings.HasPrefix(strings.ToLower(name), "cover.")
+	if !strings.HasPrefix(mimeType, "image/") || data.size == 0 || (info.Cover != nil && !isCover) {
+		return nil
+	}
+
+	info.Cover, err = readBlock(r, data.offset, data.size)
+	return err
+}
+
+// readMatroskaTrack fills dimensions of the info if the track is the first video track
+func readMatroskaTrack(r io.ReaderAt, track ebmlElement, info *VideoInfo) error {
+	if info.Width != 0 {
+		return nil
+	}
+
+	var (
+		trackType     uint64
+		width, height uint64
+	)
+
+	err := walkEBML(r, track.offset, track.offset+track.size, func(e ebmlElement) (err error) {
+		switch e.id {
+		case ebmlIDTrackType:
+			trackType, err = readEBMLUint(r, e)
+		case ebmlIDVideo:
+			err = walkEBML(r, e.offset, e.offset+e.size, func(e ebmlElement) (err error) {
+				switch e.id {
+				case ebmlIDPixelWidth:
+					width, err = readEBMLUint(r, e)
+				case ebmlIDPixelHeight:
+					height, err = readEBMLUint(r, e)
+				}
+				return err
+			})
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if trackType == matroskaTrackTypeVideo {
+		info.Width, info.Height = int(width), int(height)
+	}
+
+	return nil
+}
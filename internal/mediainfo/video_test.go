@@ -0,0 +1,117 @@
+package mediainfo_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aleksander-git/telegram-torrent/internal/mediainfo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeVideo(t *testing.T) {
+	var tests = []struct {
+		name string
+		file []byte
+		want mediainfo.VideoInfo
+	}{
+		{
+			name: "mp4_faststart",
+			file: bytes.Join([][]byte{
+				mp4Box("ftyp", []byte("isom\x00\x00\x02\x00")),
+				mp4Box("moov", mvhd(600, 6000), soundTrak(), videoTrak(1920, 1080, false)),
+				mp4Box("mdat", make([]byte, 64)),
+			}, nil),
+			want: mediainfo.VideoInfo{
+				Width:      1920,
+				Height:     1080,
+				Duration:   10 * time.Second,
+				Streamable: true,
+			},
+		},
+		{
+			name: "mp4_moov_at_end_rotated",
+			file: bytes.Join([][]byte{
+				mp4Box("ftyp", []byte("isom\x00\x00\x02\x00")),
+				mp4Box("mdat", make([]byte, 64)),
+				mp4Box("moov", mvhd(1000, 1500), videoTrak(1280, 720, true)),
+			}, nil),
+			want: mediainfo.VideoInfo{
+				Width:    720,
+				Height:   1280,
+				Duration: 1500 * time.Millisecond,
+			},
+		},
+		{
+			name: "matroska",
+			file: bytes.Join([][]byte{
+				ebml(0x1A45DFA3, ebml(0x4282, []byte("webm"))),
+				ebml(0x18538067,
+					ebml(0x1549A966,
+						ebml(0x2AD7B1, []byte{0x0F, 0x42, 0x40}),
+						ebml(0x4489, binary.BigEndian.AppendUint64(nil, math.Float64bits(42500))),
+					),
+					ebml(0x1654AE6B,
+						ebml(0xAE, ebml(0x83, []byte{2})),
+						ebml(0xAE, ebml(0x83, []byte{1}), ebml(0xE0, ebml(0xB0, []byte{0x03, 0x20}), ebml(0xBA, []byte{0x02, 0x58}))),
+					),
+					ebml(0x1F43B675, make([]byte, 32)),
+				),
+			}, nil),
+			want: mediainfo.VideoInfo{
+				Width:    800,
+				Height:   600,
+				Duration: 42500 * time.Millisecond,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info, err := mediainfo.ProbeVideo(bytes.NewReader(test.file))
+			require.NoError(t, err)
+			require.Equal(t, test.want, info)
+		})
+	}
+}
+
+func hdlr(handler string) []byte {
+	data := make([]byte, 25)
+	copy(data[8:12], handler)
+	return mp4Box("hdlr", data)
+}
+
+func soundTrak() []byte {
+	return mp4Box("trak", mp4Box("tkhd", make([]byte, 84)), mp4Box("mdia", hdlr("soun")))
+}
+
+func videoTrak(width, height uint32, rotated bool) []byte {
+	tkhd := make([]byte, 84)
+	if !rotated {
+		binary.BigEndian.PutUint32(tkhd[40:], 0x10000)
+	}
+	binary.BigEndian.PutUint32(tkhd[76:], width<<16)
+	binary.BigEndian.PutUint32(tkhd[80:], height<<16)
+
+	return mp4Box("trak", mp4Box("tkhd", tkhd), mp4Box("mdia", hdlr("vide")))
+}
+
+func ebml(id uint32, content ...[]byte) []byte {
+	body := bytes.Join(content, nil)
+
+	var element []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(element) > 0 {
+			element = append(element, b)
+		}
+	}
+
+	// 8-byte size: length marker followed by 7 bytes of value
+	size := binary.BigEndian.AppendUint64(nil, uint64(len(body)))
+	size[0] = 0x01
+
+	element = append(element, size...)
+	return append(element, body...)
+}
@@ -0,0 +1,107 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+	"github.com/aleksander-git/telegram-torrent/internal/settings"
+)
+
+// TorrentStore provides users of the torrent and stores its metadata
+type TorrentStore interface {
+	GetTorrentUserIDs(ctx context.Context, torrentLink string) ([]int64, error)
+	UpdateTorrentInfo(ctx context.Context, torrentLink string, name string, size int64) error
+	UpdateTorrentError(ctx context.Context, torrentLink string, reason string) error
+}
+
+// TorrentCheck rejects torrents exceeding the size limits as soon as their metadata is known.
+// It can be passed to loader.Loader.WithCheck
+type TorrentCheck struct {
+	checker *Checker
+	store   TorrentStore
+}
+
+var _ loader.Checker = (*TorrentCheck)(nil)
+
+func NewTorrentCheck(checker *Checker, store TorrentStore) *TorrentCheck {
+	return &TorrentCheck{
+		checker: checker,
+		store:   store,
+	}
+}
+
+// CheckTorrent stores name and size of the torrent and checks them against the quotas.
+// The torrent is downloaded once for all users who added it,
+// so it's rejected only if it doesn't fit the quota of any of them.
+// The reason of the rejection is stored as the torrent error
+func (c *TorrentCheck) CheckTorrent(ctx context.Context, magnetUri string, info loader.Info) error {
+	if err := c.store.UpdateTorrentInfo(ctx, magnetUri, info.Name, info.Size); err != nil {
+		return fmt.Errorf("c.store.UpdateTorrentInfo(ctx, %q): %w", magnetUri, err)
+	}
+
+	err := c.check(ctx, magnetUri, info)
+	if errors.Is(err, ErrTooLarge) || errors.Is(err, ErrExceeded) {
+		if err := c.store.UpdateTorrentError(ctx, magnetUri, err.Error()); err != nil {
+			return fmt.Errorf("c.store.UpdateTorrentError(ctx, %q): %w", magnetUri, err)
+		}
+	}
+
+	return err
+}
+
+func (c *TorrentCheck) check(ctx context.Context, magnetUri string, info loader.Info) error {
+	if err := c.checker.Size(ctx, info.Size); err != nil {
+		return err
+	}
+
+	userIDs, err := c.store.GetTorrentUserIDs(ctx, magnetUri)
+	if err != nil {
+		return fmt.Errorf("c.store.GetTorrentUserIDs(ctx, %q): %w", magnetUri, err)
+	}
+
+	var firstErr error
+	for _, userID := range userIDs {
+		err := c.checker.Bytes(ctx, userID, magnetUri, info.Size)
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, ErrExceeded) {
+			return err
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// DownloadRate returns torrent_download_rate of the torrent, the most generous among its users.
+// It can be passed to loader.Bandwidth.WithTorrentLimit
+func (c *TorrentCheck) DownloadRate(ctx context.Context, magnetUri string) (int64, error) {
+	userIDs, err := c.store.GetTorrentUserIDs(ctx, magnetUri)
+	if err != nil {
+		return 0, fmt.Errorf("c.store.GetTorrentUserIDs(ctx, %q): %w", magnetUri, err)
+	}
+
+	var rate int64
+	for _, userID := range userIDs {
+		userRate, err := c.checker.settings.Int(ctx, settings.TorrentDownloadRate, userID)
+		if err != nil {
+			return 0, fmt.Errorf("c.checker.settings.Int(%q): %w", settings.TorrentDownloadRate.Name, err)
+		}
+
+		// 0 means no limit
+		if userRate == 0 {
+			return 0, nil
+		}
+
+		rate = max(rate, userRate)
+	}
+
+	return rate, nil
+}
@@ -0,0 +1,182 @@
+// Package quota limits how much users can download
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aleksander-git/telegram-torrent/internal/database/backend"
+	"github.com/aleksander-git/telegram-torrent/internal/settings"
+)
+
+const (
+	// Window is a period daily quotas are counted over
+	Window = 24 * time.Hour
+	// MonthWindow is a period monthly quotas are counted over
+	MonthWindow = 30 * Window
+)
+
+var (
+	// ErrExceeded is returned if the torrent doesn't fit the user's quota
+	ErrExceeded = errors.New("quota exceeded")
+	// ErrTooLarge is returned if the torrent is larger than allowed for everyone
+	ErrTooLarge = errors.New("torrent is too large")
+)
+
+// Store provides users and torrents they added
+type Store interface {
+	GetUser(ctx context.Context, id int64) (backend.User, error)
+	GetUserTorrentTimes(ctx context.Context, userID int64, since time.Time) ([]time.Time, error)
+	GetUserTorrentBytes(ctx context.Context, userID int64, since time.Time, torrentLink string) (int64, error)
+}
+
+// Usage is a state of the user's quota
+type Usage struct {
+	Used int64
+	// Limit is 0 if there is no limit
+	Limit int64
+	// ResetAt is a time the user can add a torrent again, it's zero if the quota isn't exceeded
+	ResetAt time.Time
+}
+
+// Exceeded reports whether the user can't add more torrents
+func (u Usage) Exceeded() bool {
+	return u.Limit > 0 && u.Used >= u.Limit
+}
+
+// Checker checks user quotas against the settings
+type Checker struct {
+	settings *settings.Settings
+	store    Store
+	// now returns the current time in UTC, timestamps are stored in UTC without a time zone
+	now func() time.Time
+}
+
+func New(s *settings.Settings, store Store) *Checker {
+	return &Checker{
+		settings: s,
+		store:    store,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// WithClock replaces the current time source, e.g. in tests
+func (c *Checker) WithClock(now func() time.Time) *Checker {
+	c.now = now
+
+	return c
+}
+
+// Torrents returns usage of the torrents_per_day quota.
+//
+// The limit is the user's (or global) torrents_per_day
+// increased by torrents_per_priority for every priority level of the user
+func (c *Checker) Torrents(ctx context.Context, userID int64) (Usage, error) {
+	limit, err := c.torrentsLimit(ctx, userID)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	now := c.now()
+	times, err := c.store.GetUserTorrentTimes(ctx, userID, now.Add(-Window))
+	if err != nil {
+		return Usage{}, fmt.Errorf("c.store.GetUserTorrentTimes(ctx, %d): %w", userID, err)
+	}
+
+	usage := Usage{
+		Used:  int64(len(times)),
+		Limit: limit,
+	}
+
+	if usage.Exceeded() {
+		// a torrent can be added when all but limit-1 torrents are out of the window
+		usage.ResetAt = times[usage.Used-usage.Limit].Add(Window)
+	}
+
+	return usage, nil
+}
+
+func (c *Checker) torrentsLimit(ctx context.Context, userID int64) (int64, error) {
+	limit, err := c.settings.Int(ctx, settings.TorrentsPerDay, userID)
+	if err != nil {
+		return 0, fmt.Errorf("c.settings.Int(%q): %w", settings.TorrentsPerDay.Name, err)
+	}
+
+	if limit == 0 {
+		return 0, nil
+	}
+
+	user, err := c.store.GetUser(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return limit, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("c.store.GetUser(ctx, %d): %w", userID, err)
+	}
+
+	if user.Priority <= 0 {
+		return limit, nil
+	}
+
+	bonus, err := c.settings.Int(ctx, settings.TorrentsPerPriority, userID)
+	if err != nil {
+		return 0, fmt.Errorf("c.settings.Int(%q): %w", settings.TorrentsPerPriority.Name, err)
+	}
+
+	return limit + int64(user.Priority)*bonus, nil
+}
+
+// Size returns ErrTooLarge if the torrent exceeds max_torrent_size
+func (c *Checker) Size(ctx context.Context, size int64) error {
+	maxSize, err := c.settings.Int(ctx, settings.MaxTorrentSize, 0)
+	if err != nil {
+		return fmt.Errorf("c.settings.Int(%q): %w", settings.MaxTorrentSize.Name, err)
+	}
+
+	if maxSize > 0 && size > maxSize {
+		return fmt.Errorf("%w: %d bytes, maximum is %d bytes", ErrTooLarge, size, maxSize)
+	}
+
+	return nil
+}
+
+// Bytes returns ErrExceeded if the torrent torrentLink of the size
+// doesn't fit the user's bytes_per_day or bytes_per_month quota
+func (c *Checker) Bytes(ctx context.Context, userID int64, torrentLink string, size int64) error {
+	quotas := []struct {
+		key    settings.Key
+		window time.Duration
+	}{
+		{key: settings.BytesPerDay, window: Window},
+		{key: settings.BytesPerMonth, window: MonthWindow},
+	}
+
+	now := c.now()
+	for _, quota := range quotas {
+		limit, err := c.settings.Int(ctx, quota.key, userID)
+		if err != nil {
+			return fmt.Errorf("c.settings.Int(%q): %w", quota.key.Name, err)
+		}
+
+		if limit == 0 {
+			continue
+		}
+
+		used, err := c.store.GetUserTorrentBytes(ctx, userID, now.Add(-quota.window), torrentLink)
+		if err != nil {
+			return fmt.Errorf("c.store.GetUserTorrentBytes(ctx, %d): %w", userID, err)
+		}
+
+		if used+size > limit {
+			return fmt.Errorf("%w: %s of user %d is %d bytes, %d bytes are used, torrent has %d bytes",
+				ErrExceeded, quota.key.Name, userID, limit, used, size)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package quota_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/database/backend"
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+	"github.com/aleksander-git/telegram-torrent/internal/quota"
+	"github.com/aleksander-git/telegram-torrent/internal/settings"
+)
+
+type fakeStore struct {
+	settings map[string]string
+	priority int32
+	times    []time.Time
+	// bytes are used by every user
+	bytes int64
+
+	userIDs []int64
+	info    loader.Info
+	reason  string
+
+	// since is the last time torrents are requested since
+	since time.Time
+}
+
+func (f *fakeStore) GetSetting(_ context.Context, _ int64, key string) (string, error) {
+	if value, ok := f.settings[key]; ok {
+		return value, nil
+	}
+	return "", sql.ErrNoRows
+}
+
+func (f *fakeStore) SetSetting(_ context.Context, _ int64, key string, value string) error {
+	f.settings[key] = value
+	return nil
+}
+
+func (f *fakeStore) DeleteSetting(_ context.Context, _ int64, key string) error {
+	delete(f.settings, key)
+	return nil
+}
+
+func (f *fakeStore) GetUser(_ context.Context, id int64) (backend.User, error) {
+	if f.priority == 0 {
+		return backend.User{}, sql.ErrNoRows
+	}
+	return backend.User{ID: id, Priority: f.priority}, nil
+}
+
+func (f *fakeStore) GetUserTorrentTimes(_ context.Context, _ int64, since time.Time) ([]time.Time, error) {
+	f.since = since
+
+	var times []time.Time
+	for _, t := range f.times {
+		if t.After(since) {
+			times = append(times, t)
+		}
+	}
+	return times, nil
+}
+
+func (f *fakeStore) GetUserTorrentBytes(_ context.Context, _ int64, _ time.Time, _ string) (int64, error) {
+	return f.bytes, nil
+}
+
+func (f *fakeStore) GetTorrentUserIDs(_ context.Context, _ string) ([]int64, error) {
+	return f.userIDs, nil
+}
+
+func (f *fakeStore) UpdateTorrentInfo(_ context.Context, _ string, name string, size int64) error {
+	f.info = loader.Info{Name: name, Size: size}
+	return nil
+}
+
+func (f *fakeStore) UpdateTorrentError(_ context.Context, _ string, reason string) error {
+	f.reason = reason
+	return nil
+}
+
+func TestTorrents(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	hoursAgo := func(hours ...int) []time.Time {
+		times := make([]time.Time, 0, len(hours))
+		for _, h := range hours {
+			times = append(times, now.Add(-time.Duration(h)*time.Hour))
+		}
+		return times
+	}
+
+	tests := []struct {
+		name     string
+		settings map[string]string
+		priority int32
+		times    []time.Time
+		want     quota.Usage
+	}{
+		{
+			name:  "default_limit",
+			times: hoursAgo(30, 5, 1),
+			want:  quota.Usage{Used: 2, Limit: 10},
+		}, {
+			name:     "exceeded",
+			settings: map[string]string{"torrents_per_day": "3"},
+			times:    hoursAgo(20, 10, 5, 1),
+			want:     quota.Usage{Used: 4, Limit: 3, ResetAt: now.Add(14 * time.Hour)},
+		}, {
+			name:     "priority_bonus",
+			settings: map[string]string{"torrents_per_day": "2", "torrents_per_priority": "1"},
+			priority: 2,
+			times:    hoursAgo(3, 2, 1),
+			want:     quota.Usage{Used: 3, Limit: 4},
+		}, {
+			name:     "unlimited",
+			settings: map[string]string{"torrents_per_day": "0"},
+			priority: 1,
+			times:    hoursAgo(3, 2, 1),
+			want:     quota.Usage{Used: 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &fakeStore{settings: test.settings, priority: test.priority, times: test.times}
+			checker := quota.New(settings.New(store), store).WithClock(func() time.Time { return now })
+
+			usage, err := checker.Torrents(context.Background(), 1)
+			require.NoError(t, err)
+			require.Equal(t, test.want, usage)
+			require.Equal(t, !test.want.ResetAt.IsZero(), usage.Exceeded())
+		})
+	}
+}
+
+func TestTorrents_UTC(t *testing.T) {
+	store := &fakeStore{}
+	checker := quota.New(settings.New(store), store)
+
+	_, err := checker.Torrents(context.Background(), 1)
+	require.NoError(t, err)
+	// timestamps are stored in UTC without a time zone
+	require.Equal(t, time.UTC, store.since.Location())
+}
+
+func TestTorrentCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		bytes    int64
+		userIDs  []int64
+		size     int64
+		wantErr  error
+	}{
+		{
+			name:    "no_limits",
+			userIDs: []int64{1},
+			size:    1 << 40,
+		}, {
+			name:     "too_large",
+			settings: map[string]string{"max_torrent_size": "1000"},
+			userIDs:  []int64{1},
+			size:     1001,
+			wantErr:  quota.ErrTooLarge,
+		}, {
+			name:     "fits_day",
+			settings: map[string]string{"bytes_per_day": "1000"},
+			bytes:    500,
+			userIDs:  []int64{1},
+			size:     500,
+		}, {
+			name:     "day_exceeded",
+			settings: map[string]string{"bytes_per_day": "1000"},
+			bytes:    500,
+			userIDs:  []int64{1, 2},
+			size:     501,
+			wantErr:  quota.ErrExceeded,
+		}, {
+			name:     "month_exceeded",
+			settings: map[string]string{"bytes_per_day": "1000", "bytes_per_month": "2000"},
+			bytes:    1900,
+			userIDs:  []int64{1},
+			size:     200,
+			wantErr:  quota.ErrExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.settings == nil {
+				test.settings = map[string]string{}
+			}
+
+			store := &fakeStore{settings: test.settings, bytes: test.bytes, userIDs: test.userIDs}
+			check := quota.NewTorrentCheck(quota.New(settings.New(store), store), store)
+
+			info := loader.Info{Name: "torrent", Size: test.size}
+			err := check.CheckTorrent(context.Background(), "magnet:?xt=urn:btih:0", info)
+			require.ErrorIs(t, err, test.wantErr)
+			require.Equal(t, info, store.info)
+
+			if test.wantErr != nil {
+				require.Equal(t, err.Error(), store.reason)
+			} else {
+				require.Empty(t, store.reason)
+			}
+		})
+	}
+}
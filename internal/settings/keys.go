@@ -0,0 +1,229 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// Type is a type of the setting value
+type Type string
+
+const (
+	TypeString Type = "string"
+	TypeInt    Type = "int"
+	TypeBool   Type = "bool"
+)
+
+// Scope defines who the setting value belongs to
+type Scope int
+
+const (
+	// ScopeGlobal settings have a single value for everyone
+	ScopeGlobal Scope = iota
+	// ScopeUser settings have a global value which can be overridden for a user
+	ScopeUser
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeGlobal:
+		return "global"
+	case ScopeUser:
+		return "user"
+	default:
+		return "Scope(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// Key declares a known setting
+type Key struct {
+	Name  string
+	Type  Type
+	Scope Scope
+	// Default is used if the setting isn't stored in the database
+	Default string
+	// Validate checks the value after it is parsed as Type, optional
+	Validate func(value string) error
+}
+
+var (
+	// ChannelID is a Bot API ID of the channel users must be subscribed to
+	ChannelID = register(Key{
+		Name:    "channel_id",
+		Type:    TypeInt,
+		Scope:   ScopeGlobal,
+		Default: "-1002184825487",
+	})
+
+	// ChannelLink is a link to the channel users must be subscribed to
+	ChannelLink = register(Key{
+		Name:     "channel_link",
+		Type:     TypeString,
+		Scope:    ScopeGlobal,
+		Default:  "https://t.me/torrent_tbot",
+		Validate: validateURL,
+	})
+
+	// TorrentsPerDay is a number of torrents a user can add in 24 hours, 0 means no limit
+	TorrentsPerDay = register(Key{
+		Name:     "torrents_per_day",
+		Type:     TypeInt,
+		Scope:    ScopeUser,
+		Default:  "10",
+		Validate: nonNegative,
+	})
+
+	// BytesPerDay is a total size of torrents a user can add in 24 hours, 0 means no limit
+	BytesPerDay = register(Key{
+		Name:     "bytes_per_day",
+		Type:     TypeInt,
+		Scope:    ScopeUser,
+		Default:  "0",
+		Validate: nonNegative,
+	})
+
+	// BytesPerMonth is a total size of torrents a user can add in 30 days, 0 means no limit
+	BytesPerMonth = register(Key{
+		Name:     "bytes_per_month",
+		Type:     TypeInt,
+		Scope:    ScopeUser,
+		Default:  "0",
+		Validate: nonNegative,
+	})
+
+	// MaxTorrentSize is a maximum size of a torrent in bytes, 0 means no limit
+	MaxTorrentSize = register(Key{
+		Name:     "max_torrent_size",
+		Type:     TypeInt,
+		Scope:    ScopeGlobal,
+		Default:  "0",
+		Validate: nonNegative,
+	})
+
+	// DownloadRate is a download rate of the torrent client in bytes per second, 0 means no limit
+	DownloadRate = register(Key{
+		Name:     "download_rate",
+		Type:     TypeInt,
+		Scope:    ScopeGlobal,
+		Default:  "0",
+		Validate: nonNegative,
+	})
+
+	// UploadRate is an upload rate of the torrent client in bytes per second, 0 means no limit
+	UploadRate = register(Key{
+		Name:     "upload_rate",
+		Type:     TypeInt,
+		Scope:    ScopeGlobal,
+		Default:  "0",
+		Validate: nonNegative,
+	})
+
+	// TorrentDownloadRate is a download rate of a user's torrent in bytes per second, 0 means no limit
+	TorrentDownloadRate = register(Key{
+		Name:     "torrent_download_rate",
+		Type:     TypeInt,
+		Scope:    ScopeUser,
+		Default:  "0",
+		Validate: nonNegative,
+	})
+
+	// TorrentsPerPriority is a number of torrents added to TorrentsPerDay for every priority level of a user
+	TorrentsPerPriority = register(Key{
+		Name:     "torrents_per_priority",
+		Type:     TypeInt,
+		Scope:    ScopeGlobal,
+		Default:  "5",
+		Validate: nonNegative,
+	})
+)
+
+var registry = make(map[string]Key)
+
+// register adds the key to the registry. It panics on duplicate names and invalid defaults
+func register(key Key) Key {
+	if _, ok := registry[key.Name]; ok {
+		panic(fmt.Sprintf("settings: key %q is registered twice", key.Name))
+	}
+
+	if err := key.check(key.Default); err != nil {
+		panic(fmt.Sprintf("settings: default of %q: %s", key.Name, err))
+	}
+
+	registry[key.Name] = key
+
+	return key
+}
+
+// Lookup returns the registered key by its name
+func Lookup(name string) (Key, bool) {
+	key, ok := registry[name]
+	return key, ok
+}
+
+// Keys returns all registered keys sorted by name
+func Keys() []Key {
+	keys := make([]Key, 0, len(registry))
+	for _, key := range registry {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Name < keys[j].Name
+	})
+
+	return keys
+}
+
+// check reports whether the value can be stored in the setting
+func (k Key) check(value string) error {
+	var err error
+	switch k.Type {
+	case TypeString:
+	case TypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case TypeBool:
+		_, err = strconv.ParseBool(value)
+	default:
+		err = fmt.Errorf("unknown type %q", k.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %q is not %s: %w", ErrInvalidValue, value, k.Type, err)
+	}
+
+	if k.Validate != nil {
+		if err := k.Validate(value); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidValue, err)
+		}
+	}
+
+	return nil
+}
+
+func nonNegative(value string) error {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	if n < 0 {
+		return errors.New("value must not be negative")
+	}
+
+	return nil
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", value)
+	}
+
+	return nil
+}
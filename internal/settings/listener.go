@@ -0,0 +1,136 @@
+package settings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NotifyChannel is a Postgres channel the settings trigger notifies with names of changed settings
+const NotifyChannel = "settings_changed"
+
+const (
+	listenerMinReconnect = 10 * time.Second
+	listenerMaxReconnect = time.Minute
+	listenerPingInterval = 90 * time.Second
+)
+
+// ChangeHandler is called with name of the changed setting.
+// Empty name means that any setting could change, e.g. after reconnect
+type ChangeHandler func(ctx context.Context, name string) error
+
+// Listener invalidates cached settings when they are changed in the database
+// by any instance or by hand
+type Listener struct {
+	log      *slog.Logger
+	settings *Settings
+	listener *pq.Listener
+
+	mu       sync.Mutex
+	handlers []ChangeHandler
+}
+
+func NewListener(log *slog.Logger, connectionString string, settings *Settings) *Listener {
+	l := &Listener{
+		log:      log,
+		settings: settings,
+	}
+
+	l.listener = pq.NewListener(connectionString, listenerMinReconnect, listenerMaxReconnect, l.event)
+
+	return l
+}
+
+// Subscribe adds the handler called after the cache is invalidated,
+// e.g. to reload caption templates
+func (l *Listener) Subscribe(handler ChangeHandler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.handlers = append(l.handlers, handler)
+}
+
+// Run listens for notifications until ctx is done
+func (l *Listener) Run(ctx context.Context) error {
+	const src = "Listener.Run"
+	log := l.log.With(
+		slog.String("src", src),
+	)
+
+	if err := l.listener.Listen(NotifyChannel); err != nil {
+		return fmt.Errorf("l.listener.Listen(%q): %w", NotifyChannel, err)
+	}
+
+	// changes made before listening are missed
+	l.changed(ctx, "")
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case notification := <-l.listener.NotificationChannel():
+			// nil is received after reconnect, notifications could be lost meanwhile
+			name := ""
+			if notification != nil {
+				name = notification.Extra
+			}
+
+			log.Debug("setting changed", slog.String("name", name))
+
+			l.changed(ctx, name)
+		case <-ticker.C:
+			if err := l.listener.Ping(); err != nil {
+				log.Warn("ping failed", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
+
+func (l *Listener) changed(ctx context.Context, name string) {
+	if name == "" {
+		l.settings.InvalidateAll()
+	} else {
+		l.settings.Invalidate(name)
+	}
+
+	l.mu.Lock()
+	handlers := l.handlers
+	l.mu.Unlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, name); err != nil {
+			l.log.Error("setting change handler failed",
+				slog.String("src", "Listener.changed"),
+				slog.String("name", name),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+func (l *Listener) event(event pq.ListenerEventType, err error) {
+	const src = "Listener.event"
+	log := l.log.With(
+		slog.String("src", src),
+	)
+
+	switch event {
+	case pq.ListenerEventDisconnected:
+		log.Warn("disconnected", slog.Any("error", err))
+	case pq.ListenerEventReconnected:
+		log.Info("reconnected")
+	case pq.ListenerEventConnectionAttemptFailed:
+		log.Warn("connection attempt failed", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,192 @@
+// Package settings provides typed access to the settings table
+// with validation, defaults and caching
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+var (
+	ErrInvalidValue = errors.New("invalid setting value")
+	ErrWrongType    = errors.New("setting has another type")
+	// ErrGlobalScope is returned on attempt to set a global setting for a user
+	ErrGlobalScope = errors.New("setting can't be set for a user")
+)
+
+// Store keeps setting values. Zero userID means the global value.
+//
+// GetSetting returns the user's value falling back to the global one
+// and sql.ErrNoRows if neither is stored
+type Store interface {
+	GetSetting(ctx context.Context, userID int64, key string) (string, error)
+	SetSetting(ctx context.Context, userID int64, key string, value string) error
+	DeleteSetting(ctx context.Context, userID int64, key string) error
+}
+
+// Settings reads and writes settings through the cache
+type Settings struct {
+	store Store
+
+	mu sync.RWMutex
+	// cache holds values by setting name and user ID
+	cache map[string]map[int64]string
+	// generation is increased on every invalidation,
+	// so values read before it are not cached
+	generation uint64
+}
+
+func New(store Store) *Settings {
+	return &Settings{
+		store: store,
+		cache: make(map[string]map[int64]string),
+	}
+}
+
+// String returns value of the string setting for the user.
+// User ID is ignored for the global settings
+func (s *Settings) String(ctx context.Context, key Key, userID int64) (string, error) {
+	if key.Type != TypeString {
+		return "", fmt.Errorf("%w: %q is %s", ErrWrongType, key.Name, key.Type)
+	}
+
+	return s.get(ctx, key, userID)
+}
+
+// Int returns value of the integer setting for the user.
+// User ID is ignored for the global settings
+func (s *Settings) Int(ctx context.Context, key Key, userID int64) (int64, error) {
+	if key.Type != TypeInt {
+		return 0, fmt.Errorf("%w: %q is %s", ErrWrongType, key.Name, key.Type)
+	}
+
+	value, err := s.get(ctx, key, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// Bool returns value of the boolean setting for the user.
+// User ID is ignored for the global settings
+func (s *Settings) Bool(ctx context.Context, key Key, userID int64) (bool, error) {
+	if key.Type != TypeBool {
+		return false, fmt.Errorf("%w: %q is %s", ErrWrongType, key.Name, key.Type)
+	}
+
+	value, err := s.get(ctx, key, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return strconv.ParseBool(value)
+}
+
+// Set validates and stores the setting value. Zero userID sets the global value
+func (s *Settings) Set(ctx context.Context, key Key, userID int64, value string) error {
+	if userID != 0 && key.Scope == ScopeGlobal {
+		return fmt.Errorf("%w: %q", ErrGlobalScope, key.Name)
+	}
+
+	if err := key.check(value); err != nil {
+		return fmt.Errorf("setting %q: %w", key.Name, err)
+	}
+
+	if err := s.store.SetSetting(ctx, userID, key.Name, value); err != nil {
+		return fmt.Errorf("s.store.SetSetting(ctx, %d, %q): %w", userID, key.Name, err)
+	}
+
+	s.Invalidate(key.Name)
+
+	return nil
+}
+
+func (s *Settings) SetInt(ctx context.Context, key Key, userID int64, value int64) error {
+	if key.Type != TypeInt {
+		return fmt.Errorf("%w: %q is %s", ErrWrongType, key.Name, key.Type)
+	}
+
+	return s.Set(ctx, key, userID, strconv.FormatInt(value, 10))
+}
+
+func (s *Settings) SetBool(ctx context.Context, key Key, userID int64, value bool) error {
+	if key.Type != TypeBool {
+		return fmt.Errorf("%w: %q is %s", ErrWrongType, key.Name, key.Type)
+	}
+
+	return s.Set(ctx, key, userID, strconv.FormatBool(value))
+}
+
+// Reset removes the stored value, so the user gets the global value
+// and the global value falls back to the default
+func (s *Settings) Reset(ctx context.Context, key Key, userID int64) error {
+	if err := s.store.DeleteSetting(ctx, userID, key.Name); err != nil {
+		return fmt.Errorf("s.store.DeleteSetting(ctx, %d, %q): %w", userID, key.Name, err)
+	}
+
+	s.Invalidate(key.Name)
+
+	return nil
+}
+
+// Invalidate drops cached values of the setting for every user
+func (s *Settings) Invalidate(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.cache, name)
+	s.generation++
+}
+
+// InvalidateAll drops all cached values
+func (s *Settings) InvalidateAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache = make(map[string]map[int64]string)
+	s.generation++
+}
+
+func (s *Settings) get(ctx context.Context, key Key, userID int64) (string, error) {
+	if key.Scope == ScopeGlobal {
+		userID = 0
+	}
+
+	s.mu.RLock()
+	value, ok := s.cache[key.Name][userID]
+	generation := s.generation
+	s.mu.RUnlock()
+
+	if ok {
+		return value, nil
+	}
+
+	value, err := s.store.GetSetting(ctx, userID, key.Name)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		value = key.Default
+	case err != nil:
+		return "", fmt.Errorf("s.store.GetSetting(ctx, %d, %q): %w", userID, key.Name, err)
+	}
+
+	if err := key.check(value); err != nil {
+		return "", fmt.Errorf("stored setting %q: %w", key.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generation == generation {
+		if s.cache[key.Name] == nil {
+			s.cache[key.Name] = make(map[int64]string)
+		}
+		s.cache[key.Name][userID] = value
+	}
+
+	return value, nil
+}
@@ -0,0 +1,142 @@
+package settings_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/settings"
+)
+
+type storeKey struct {
+	userID int64
+	name   string
+}
+
+type memoryStore struct {
+	values map[storeKey]string
+	reads  int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: make(map[storeKey]string)}
+}
+
+func (m *memoryStore) GetSetting(_ context.Context, userID int64, key string) (string, error) {
+	m.reads++
+
+	if value, ok := m.values[storeKey{userID: userID, name: key}]; ok {
+		return value, nil
+	}
+	if value, ok := m.values[storeKey{name: key}]; ok {
+		return value, nil
+	}
+
+	return "", sql.ErrNoRows
+}
+
+func (m *memoryStore) SetSetting(_ context.Context, userID int64, key string, value string) error {
+	m.values[storeKey{userID: userID, name: key}] = value
+	return nil
+}
+
+func (m *memoryStore) DeleteSetting(_ context.Context, userID int64, key string) error {
+	delete(m.values, storeKey{userID: userID, name: key})
+	return nil
+}
+
+func TestSettings(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	s := settings.New(store)
+
+	// default is used and cached
+	perDay, err := s.Int(ctx, settings.TorrentsPerDay, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), perDay)
+
+	_, err = s.Int(ctx, settings.TorrentsPerDay, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, store.reads)
+
+	// global value applies to users without their own one
+	require.NoError(t, s.SetInt(ctx, settings.TorrentsPerDay, 0, 5))
+	require.NoError(t, s.SetInt(ctx, settings.TorrentsPerDay, 2, 20))
+
+	perDay, err = s.Int(ctx, settings.TorrentsPerDay, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), perDay)
+
+	perDay, err = s.Int(ctx, settings.TorrentsPerDay, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(20), perDay)
+
+	require.NoError(t, s.Reset(ctx, settings.TorrentsPerDay, 2))
+
+	perDay, err = s.Int(ctx, settings.TorrentsPerDay, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), perDay)
+
+	// the store is changed by someone else
+	store.values[storeKey{name: settings.ChannelLink.Name}] = "https://t.me/other"
+
+	link, err := s.String(ctx, settings.ChannelLink, 1)
+	require.NoError(t, err)
+	require.Equal(t, "https://t.me/other", link)
+
+	store.values[storeKey{name: settings.ChannelLink.Name}] = "https://t.me/changed"
+	s.Invalidate(settings.ChannelLink.Name)
+
+	link, err = s.String(ctx, settings.ChannelLink, 1)
+	require.NoError(t, err)
+	require.Equal(t, "https://t.me/changed", link)
+}
+
+func TestSettingsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     settings.Key
+		userID  int64
+		value   string
+		wantErr error
+	}{
+		{
+			name:    "not_int",
+			key:     settings.TorrentsPerDay,
+			value:   "ten",
+			wantErr: settings.ErrInvalidValue,
+		}, {
+			name:    "negative",
+			key:     settings.TorrentsPerDay,
+			value:   "-1",
+			wantErr: settings.ErrInvalidValue,
+		}, {
+			name:    "relative_link",
+			key:     settings.ChannelLink,
+			value:   "torrent_tbot",
+			wantErr: settings.ErrInvalidValue,
+		}, {
+			name:    "global_for_user",
+			key:     settings.ChannelID,
+			userID:  1,
+			value:   "-100",
+			wantErr: settings.ErrGlobalScope,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := settings.New(newMemoryStore())
+
+			err := s.Set(context.Background(), test.key, test.userID, test.value)
+			require.ErrorIs(t, err, test.wantErr)
+		})
+	}
+
+	s := settings.New(newMemoryStore())
+
+	_, err := s.String(context.Background(), settings.ChannelID, 0)
+	require.ErrorIs(t, err, settings.ErrWrongType)
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd)
+
+package storage
+
+// freeSpace returns number of bytes available to the process on the file system of dir
+func freeSpace(_ string) (int64, error) {
+	return 0, ErrFreeSpaceUnknown
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd
+
+package storage
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// freeSpace returns number of bytes available to the process on the file system of dir
+func freeSpace(dir string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, fmt.Errorf("syscall.Statfs(%q): %w", dir, err)
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,302 @@
+// Package storage manages disk space of the downloaded torrents
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aleksander-git/telegram-torrent/internal/database/backend"
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+)
+
+// DefaultRetention is a time data of finished torrents is kept for
+const DefaultRetention = 7 * 24 * time.Hour
+
+var (
+	// ErrNoSpace is returned if there is not enough free space for the torrent
+	ErrNoSpace = errors.New("not enough free space")
+	// ErrFreeSpaceUnknown is returned on platforms where free space can't be checked
+	ErrFreeSpaceUnknown = errors.New("free space is unknown")
+)
+
+// Store provides torrents stored in the data directory
+type Store interface {
+	GetTorrentNames(ctx context.Context) ([]backend.GetTorrentNamesRow, error)
+}
+
+type reservation struct {
+	name string
+	size int64
+}
+
+// Manager reserves space for downloads and removes data which is not needed anymore
+type Manager struct {
+	log   *slog.Logger
+	dir   string
+	store Store
+
+	minFree       int64
+	retention     time.Duration
+	removeUnknown bool
+
+	mu sync.Mutex
+	// reserved holds space of the downloading torrents by their magnet links
+	reserved map[string]reservation
+
+	freeSpace func(dir string) (int64, error)
+	now       func() time.Time
+}
+
+var (
+	_ loader.Checker  = (*Manager)(nil)
+	_ loader.Releaser = (*Manager)(nil)
+)
+
+// New creates manager of the loader's data directory dir
+func New(log *slog.Logger, dir string, store Store) *Manager {
+	return &Manager{
+		log:       log,
+		dir:       dir,
+		store:     store,
+		retention: DefaultRetention,
+		reserved:  make(map[string]reservation),
+		freeSpace: freeSpace,
+		now:       time.Now,
+	}
+}
+
+// WithMinFree sets number of bytes which must stay free after all downloads
+func (m *Manager) WithMinFree(bytes int64) *Manager {
+	m.minFree = bytes
+
+	return m
+}
+
+// WithRemoveUnknown makes Cleanup remove entries unknown in the torrents table
+// which weren't modified for the retention period, e.g. data of deleted torrents.
+// The data directory must not be shared with other files and other instances
+// must not download torrents before their names are stored then
+func (m *Manager) WithRemoveUnknown(remove bool) *Manager {
+	m.removeUnknown = remove
+
+	return m
+}
+
+// WithRetention sets time data of finished torrents is kept for, 0 keeps it forever
+func (m *Manager) WithRetention(retention time.Duration) *Manager {
+	m.retention = retention
+
+	return m
+}
+
+// CheckTorrent reserves space for the torrent or returns ErrNoSpace.
+// It can be passed to loader.Loader.WithCheck.
+//
+// Reserved space is not reduced while the torrent is downloaded,
+// so the check is conservative until Release or Remove is called
+func (m *Manager) CheckTorrent(_ context.Context, magnetUri string, info loader.Info) error {
+	const src = "Manager.CheckTorrent"
+	log := m.log.With(
+		slog.String("src", src),
+	)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.reserved[magnetUri]; ok {
+		return nil
+	}
+
+	free, err := m.freeSpace(m.dir)
+	if errors.Is(err, ErrFreeSpaceUnknown) {
+		log.Warn("free space is not checked", slog.String("dir", m.dir))
+		free = -1
+	} else if err != nil {
+		return fmt.Errorf("m.freeSpace(%q): %w", m.dir, err)
+	}
+
+	reserved := m.reservedSize()
+	if free >= 0 && free-reserved-m.minFree < info.Size {
+		return fmt.Errorf("%w: torrent has %d bytes, %d bytes are free, %d bytes are reserved",
+			ErrNoSpace, info.Size, free, reserved+m.minFree)
+	}
+
+	m.reserved[magnetUri] = reservation{name: info.Name, size: info.Size}
+
+	return nil
+}
+
+// Reserved returns number of bytes reserved for the downloading torrents
+func (m *Manager) Reserved() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.reservedSize()
+}
+
+// reservedSize must be called with mu held
+func (m *Manager) reservedSize() int64 {
+	var reserved int64
+	for _, r := range m.reserved {
+		reserved += r.size
+	}
+
+	return reserved
+}
+
+// Release frees space reserved for the torrent, e.g. when its download failed.
+// loader.Loader calls it when the torrent isn't loaded after the checks
+func (m *Manager) Release(magnetUri string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.reserved, magnetUri)
+}
+
+// Remove deletes data of the torrent named name and releases its reserved space.
+// It can be passed to loader.Seeder.WithRemove, so the data is deleted
+// after the torrent is uploaded and seeded
+func (m *Manager) Remove(magnetUri string, name string) error {
+	m.Release(magnetUri)
+
+	path, err := m.path(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("os.RemoveAll(%q): %w", path, err)
+	}
+
+	return nil
+}
+
+// Cleanup reconciles the data directory with the torrents table.
+// It removes data of failed torrents and of torrents finished more than retention ago.
+// Unknown entries are kept unless WithRemoveUnknown is set.
+// Data of the torrents with reserved space is kept
+func (m *Manager) Cleanup(ctx context.Context) error {
+	const src = "Manager.Cleanup"
+	log := m.log.With(
+		slog.String("src", src),
+	)
+
+	torrents, err := m.store.GetTorrentNames(ctx)
+	if err != nil {
+		return fmt.Errorf("m.store.GetTorrentNames(ctx): %w", err)
+	}
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return fmt.Errorf("os.ReadDir(%q): %w", m.dir, err)
+	}
+
+	// several torrents can have the same name, the data is kept if any of them needs it
+	keep := make(map[string]bool, len(torrents))
+	known := make(map[string]bool, len(torrents))
+	for _, torrent := range torrents {
+		known[torrent.Name.String] = true
+		keep[torrent.Name.String] = keep[torrent.Name.String] || !m.expired(torrent)
+	}
+
+	m.mu.Lock()
+	for _, r := range m.reserved {
+		keep[r.name] = true
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, entry := range entries {
+		name := entry.Name()
+		if keep[name] || isServiceFile(name) {
+			continue
+		}
+
+		if !known[name] && !m.unknownExpired(entry) {
+			continue
+		}
+
+		log.Info("removing torrent data",
+			slog.String("name", name),
+			slog.Bool("known", known[name]),
+		)
+
+		if err := os.RemoveAll(filepath.Join(m.dir, name)); err != nil {
+			errs = append(errs, fmt.Errorf("os.RemoveAll(%q): %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run calls Cleanup every interval until ctx is done
+func (m *Manager) Run(ctx context.Context, interval time.Duration) error {
+	const src = "Manager.Run"
+	log := m.log.With(
+		slog.String("src", src),
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Cleanup(ctx); err != nil {
+			log.Error("cleanup failed", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// expired reports whether data of the torrent isn't needed anymore
+func (m *Manager) expired(torrent backend.GetTorrentNamesRow) bool {
+	if torrent.Error.Valid {
+		return true
+	}
+
+	if !torrent.TimeFinished.Valid || m.retention == 0 {
+		return false
+	}
+
+	return m.now().Sub(torrent.TimeFinished.Time) > m.retention
+}
+
+// unknownExpired reports whether the entry unknown in the torrents table can be removed,
+// it could be written by another instance which hasn't stored the torrent name yet
+func (m *Manager) unknownExpired(entry os.DirEntry) bool {
+	if !m.removeUnknown || m.retention == 0 {
+		return false
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return false
+	}
+
+	return m.now().Sub(info.ModTime()) > m.retention
+}
+
+// path returns path of the torrent data, names escaping the data directory are rejected
+func (m *Manager) path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid torrent name %q", name)
+	}
+
+	return filepath.Join(m.dir, name), nil
+}
+
+// isServiceFile reports whether the file belongs to the torrent client, e.g. piece completion database
+func isServiceFile(name string) bool {
+	return strings.HasPrefix(name, ".torrent.")
+}
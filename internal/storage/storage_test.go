@@ -0,0 +1,118 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/database/backend"
+	"github.com/aleksander-git/telegram-torrent/internal/loader"
+	"github.com/aleksander-git/telegram-torrent/internal/storage"
+)
+
+type fakeStore []backend.GetTorrentNamesRow
+
+func (f fakeStore) GetTorrentNames(_ context.Context) ([]backend.GetTorrentNamesRow, error) {
+	return f, nil
+}
+
+func torrent(name string, finished time.Duration, failed bool) backend.GetTorrentNamesRow {
+	row := backend.GetTorrentNamesRow{
+		TorrentLink: "magnet:?dn=" + name,
+		Name:        sql.NullString{String: name, Valid: true},
+		Error:       sql.NullString{String: "failed", Valid: failed},
+	}
+
+	if finished != 0 {
+		row.TimeFinished = sql.NullTime{Time: time.Now().Add(-finished), Valid: true}
+	}
+
+	return row
+}
+
+func TestCleanup(t *testing.T) {
+	tests := []struct {
+		name          string
+		removeUnknown bool
+		left          []string
+	}{
+		{
+			name: "keep_unknown",
+			left: []string{"downloading", "fresh", "orphan", "new_orphan", "reserved", ".torrent.db"},
+		}, {
+			name:          "remove_unknown",
+			removeUnknown: true,
+			left:          []string{"downloading", "fresh", "new_orphan", "reserved", ".torrent.db"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			entries := []string{"downloading", "fresh", "expired", "failed", "orphan", "new_orphan", "reserved", ".torrent.db"}
+			for _, name := range entries {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644))
+			}
+
+			// unknown entries are removed only when they aren't modified for the retention period
+			old := time.Now().Add(-48 * time.Hour)
+			for _, name := range []string{"orphan", "reserved"} {
+				require.NoError(t, os.Chtimes(filepath.Join(dir, name), old, old))
+			}
+
+			store := fakeStore{
+				torrent("downloading", 0, false),
+				torrent("fresh", time.Hour, false),
+				torrent("expired", 48*time.Hour, false),
+				torrent("failed", 0, true),
+			}
+
+			m := storage.New(slog.Default(), dir, store).
+				WithRetention(24 * time.Hour).
+				WithRemoveUnknown(test.removeUnknown)
+
+			require.NoError(t, m.CheckTorrent(context.Background(), "magnet:?dn=reserved", loader.Info{Name: "reserved", Size: 1}))
+			require.NoError(t, m.Cleanup(context.Background()))
+
+			left, err := os.ReadDir(dir)
+			require.NoError(t, err)
+
+			var names []string
+			for _, entry := range left {
+				names = append(names, entry.Name())
+			}
+
+			require.ElementsMatch(t, test.left, names)
+
+			require.NoError(t, m.Remove("magnet:?dn=reserved", "reserved"))
+			require.NoFileExists(t, filepath.Join(dir, "reserved"))
+
+			require.Error(t, m.Remove("magnet:?dn=escape", "../escape"))
+		})
+	}
+}
+
+func TestCheckTorrent(t *testing.T) {
+	m := storage.New(slog.Default(), t.TempDir(), fakeStore{})
+
+	err := m.CheckTorrent(context.Background(), "magnet:?dn=huge", loader.Info{Name: "huge", Size: 1 << 62})
+	require.ErrorIs(t, err, storage.ErrNoSpace)
+
+	require.NoError(t, m.CheckTorrent(context.Background(), "magnet:?dn=small", loader.Info{Name: "small", Size: 1}))
+
+	// reserved torrent is not checked again, while new ones do not fit anymore
+	m.WithMinFree(1 << 62)
+	require.NoError(t, m.CheckTorrent(context.Background(), "magnet:?dn=small", loader.Info{Name: "small", Size: 1}))
+
+	err = m.CheckTorrent(context.Background(), "magnet:?dn=other", loader.Info{Name: "other", Size: 1})
+	require.ErrorIs(t, err, storage.ErrNoSpace)
+
+	m.Release("magnet:?dn=small")
+}
@@ -0,0 +1,147 @@
+package uploader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/html"
+	"github.com/gotd/td/tg"
+)
+
+// TargetResult is a result of sending a file to a single target
+type TargetResult struct {
+	Target string
+	// MessageID is an ID of the sent message, it's 0 if sending failed
+	MessageID int
+	Err       error
+}
+
+// ErrNoTargets is returned if a file is sent to an empty list of targets
+var ErrNoTargets = errors.New("no targets")
+
+// UploadMulti uploads file located on the filePath to the Telegram server once
+// and sends it to every target of targetDomains.
+//
+// The uploaded file is attached to the first reachable target and then
+// the same media is sent to every target by its file reference, so the content is transferred only once.
+// Targets which failed to attach the file get the media attached to another one.
+// Results are in the order of targetDomains. Error is returned only if the file
+// can't be uploaded at all, failures of single targets are reported in their results
+func (u *Uploader) UploadMulti(ctx context.Context, filePath string, targetDomains []string) ([]TargetResult, error) {
+	const src = "Uploader.UploadMulti"
+	log := u.log.With(
+		slog.String("src", src),
+	)
+
+	log.Debug("uploading file", slog.String("path", filePath), slog.Any("targets", targetDomains))
+
+	content, closer, err := openSource(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("openSource(%q): %w", filePath, err)
+	}
+	defer closer.Close()
+
+	return u.sendMulti(ctx, content, targetDomains)
+}
+
+// sendMulti uploads the source once and sends it to every target
+func (u *Uploader) sendMulti(ctx context.Context, content source, targetDomains []string) ([]TargetResult, error) {
+	const src = "Uploader.sendMulti"
+	log := u.log.With(
+		slog.String("src", src),
+	)
+
+	if len(targetDomains) == 0 {
+		return nil, ErrNoTargets
+	}
+
+	if u.templateErr != nil {
+		return nil, fmt.Errorf("invalid message template: %w", u.templateErr)
+	}
+
+	file := u.describe(content)
+
+	// caption isn't used by messages.uploadMedia, every target gets its own one
+	uploaded, err := u.upload(ctx, content, file, "")
+	if err != nil {
+		return nil, fmt.Errorf("u.upload(ctx, %q): %w", content.name, err)
+	}
+
+	results := make([]TargetResult, len(targetDomains))
+	for i, target := range targetDomains {
+		results[i].Target = target
+	}
+
+	// the uploaded file is attached to the first target that accepts it
+	var media tg.MessageMediaClass
+	for i, target := range targetDomains {
+		media, err = u.resolver.Resolve(target).UploadMedia(ctx, uploaded)
+		if err == nil {
+			break
+		}
+
+		log.Warn("failed to attach file to target",
+			slog.String("name", content.name),
+			slog.String("target", target),
+			slog.String("error", err.Error()),
+		)
+		results[i].Err = fmt.Errorf("failed to attach file %q to target %q: %w", content.name, target, err)
+	}
+	if media == nil {
+		return results, fmt.Errorf("failed to attach file %q to any of %d targets: %w", content.name, len(targetDomains), err)
+	}
+
+	// targets which failed to attach the file are retried with the stored media
+	for i, target := range targetDomains {
+		results[i].MessageID, results[i].Err = u.resend(ctx, file, media, target)
+		if results[i].Err != nil {
+			log.Warn("failed to send file to target",
+				slog.String("name", content.name),
+				slog.String("target", target),
+				slog.String("error", results[i].Err.Error()),
+			)
+		}
+	}
+
+	return results, nil
+}
+
+// resend sends the media already stored on the Telegram server to targetDomain
+// with the caption rendered for this target and returns ID of the sent message
+func (u *Uploader) resend(ctx context.Context, file fileInfo, media tg.MessageMediaClass, targetDomain string) (int, error) {
+	msg, err := u.caption(file, targetDomain)
+	if err != nil {
+		return 0, fmt.Errorf("u.caption(%q, %q): %w", file.path, targetDomain, err)
+	}
+
+	option, err := storedMedia(media, msg)
+	if err != nil {
+		return 0, fmt.Errorf("storedMedia(): %w", err)
+	}
+
+	updates, err := u.resolver.Resolve(targetDomain).Media(ctx, option)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send file %q to target %q: %w", file.path, targetDomain, err)
+	}
+
+	return u.sentMessageID(updates, file.path, targetDomain), nil
+}
+
+// storedMedia builds media referring to the document or photo stored on the Telegram server
+func storedMedia(media tg.MessageMediaClass, msg string) (message.MediaOption, error) {
+	switch media := media.(type) {
+	case *tg.MessageMediaDocument:
+		if document, ok := media.Document.(*tg.Document); ok {
+			return message.Document(document, html.String(nil, msg)), nil
+		}
+	case *tg.MessageMediaPhoto:
+		if photo, ok := media.Photo.(*tg.Photo); ok {
+			return message.Photo(photo, html.String(nil, msg)), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected uploaded media %T", media)
+}
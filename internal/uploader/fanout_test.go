@@ -0,0 +1,114 @@
+package uploader_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/peer"
+	tduploader "github.com/gotd/td/telegram/uploader"
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/uploader"
+)
+
+type fileUploader struct{}
+
+func (fileUploader) Upload(_ context.Context, _ *tduploader.Upload) (tg.InputFileClass, error) {
+	return &tg.InputFile{ID: 1, Parts: 1, Name: "hello.txt"}, nil
+}
+
+// channels resolves targets to channels with IDs from the map
+type channels struct {
+	sender *message.Sender
+	ids    map[string]int64
+}
+
+func (c channels) Resolve(from string, _ ...peer.PromiseDecorator) *message.RequestBuilder {
+	return c.sender.To(&tg.InputPeerChannel{ChannelID: c.ids[from]})
+}
+
+// invoker fails requests to the channels from the maps and answers others.
+// Sent media get IDs of the channels multiplied by 10
+type invoker struct {
+	failUpload map[int64]bool
+	failSend   map[int64]bool
+}
+
+func (i invoker) Invoke(_ context.Context, input bin.Encoder, output bin.Decoder) error {
+	var result bin.Encoder
+
+	switch request := input.(type) {
+	case *tg.MessagesUploadMediaRequest:
+		if i.failUpload[channelID(request.Peer)] {
+			return errors.New("CHAT_WRITE_FORBIDDEN")
+		}
+		result = &tg.MessageMediaDocument{Document: &tg.Document{ID: 1, FileReference: []byte{1}}}
+	case *tg.MessagesSendMediaRequest:
+		id := channelID(request.Peer)
+		if i.failSend[id] {
+			return errors.New("CHAT_WRITE_FORBIDDEN")
+		}
+		result = &tg.Updates{Updates: []tg.UpdateClass{
+			&tg.UpdateNewChannelMessage{Message: &tg.Message{ID: int(id) * 10, PeerID: &tg.PeerChannel{ChannelID: id}}},
+		}}
+	case *tg.MessagesSendMessageRequest:
+		// updates without the sent message
+		result = &tg.Updates{}
+	default:
+		return errors.New("unexpected request")
+	}
+
+	var buf bin.Buffer
+	if err := result.Encode(&buf); err != nil {
+		return err
+	}
+
+	return output.Decode(&buf)
+}
+
+func channelID(p tg.InputPeerClass) int64 {
+	if channel, ok := p.(*tg.InputPeerChannel); ok {
+		return channel.ChannelID
+	}
+	return 0
+}
+
+func TestUploader_UploadMulti(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "hello.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("hello"), 0o644))
+
+	sender := message.NewSender(tg.NewClient(invoker{
+		failUpload: map[int64]bool{1: true, 3: true},
+		failSend:   map[int64]bool{3: true},
+	}))
+	resolver := channels{sender: sender, ids: map[string]int64{"first": 1, "second": 2, "third": 3}}
+
+	u := uploader.New(slog.Default(), fileUploader{}, resolver)
+
+	results, err := u.UploadMulti(context.Background(), filePath, []string{"first", "second", "third"})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// the first target failed to attach the file, but gets the media attached to the second one
+	require.NoError(t, results[0].Err)
+	require.Equal(t, 10, results[0].MessageID)
+
+	require.NoError(t, results[1].Err)
+	require.Equal(t, 20, results[1].MessageID)
+
+	require.Error(t, results[2].Err)
+	require.Zero(t, results[2].MessageID)
+
+	_, err = u.UploadMulti(context.Background(), filePath, []string{"first", "third"})
+	require.Error(t, err)
+
+	_, err = u.UploadMulti(context.Background(), filePath, nil)
+	require.ErrorIs(t, err, uploader.ErrNoTargets)
+}
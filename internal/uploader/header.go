@@ -0,0 +1,169 @@
+package uploader
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Torrent describes downloaded torrent to post
+type Torrent struct {
+	Name     string
+	InfoHash string
+	// Link is a magnet link of the torrent, IDs of the post are stored by it, see WithPostStore
+	Link string
+	// Dir is a local directory the files are downloaded to
+	Dir   string
+	Files []TorrentFile
+	// Tags are hashtags (without #) added to the header post
+	// along with tags of the kinds of the files, e.g. #video
+	Tags []string
+}
+
+// TorrentFile is a file of the torrent
+type TorrentFile struct {
+	// Path is a path of the file relative to Torrent.Dir
+	Path string
+	Size int64
+}
+
+// Post is IDs of the messages the torrent is posted with
+type Post struct {
+	// TopicID is 0 if the torrent isn't posted in a forum topic
+	TopicID  int
+	HeaderID int
+	// FileIDs are IDs of the replies to the header with the files
+	FileIDs []int
+}
+
+// Size returns total size of the torrent files
+func (t Torrent) Size() int64 {
+	var size int64
+	for _, file := range t.Files {
+		size += file.Size
+	}
+
+	return size
+}
+
+// maxHeaderSize is a limit of header length (4096 characters in Telegram)
+// leaving the room for the file tree truncation note
+const maxHeaderSize = 3900
+
+// headerText renders HTML of the header post: name, size, file tree, hashtags and infohash.
+// The file tree is truncated to fit Telegram message length limit
+func headerText(torrent Torrent) string {
+	var head, tail strings.Builder
+
+	fmt.Fprintf(&head, "<b>%s</b>\n", html.EscapeString(torrent.Name))
+	fmt.Fprintf(&head, "Размер: %s, файлов: %d\n", formatSize(torrent.Size()), len(torrent.Files))
+
+	if tags := hashtags(torrent); len(tags) > 0 {
+		fmt.Fprintf(&tail, "\n%s\n", strings.Join(tags, " "))
+	}
+	if torrent.InfoHash != "" {
+		fmt.Fprintf(&tail, "\n<code>%s</code>", html.EscapeString(torrent.InfoHash))
+	}
+
+	// HTML tags and multibyte characters are counted too, it keeps a margin to the limit
+	budget := maxHeaderSize - head.Len() - tail.Len()
+
+	lines := fileTree(torrent.Files)
+	var tree strings.Builder
+	for i, line := range lines {
+		line = html.EscapeString(line) + "\n"
+		if tree.Len()+len(line) > budget {
+			fmt.Fprintf(&tree, "… и ещё %d\n", len(lines)-i)
+			break
+		}
+		tree.WriteString(line)
+	}
+
+	if tree.Len() > 0 {
+		fmt.Fprintf(&head, "\n<pre>%s</pre>\n", strings.TrimSuffix(tree.String(), "\n"))
+	}
+
+	return head.String() + tail.String()
+}
+
+// fileTree returns lines of the indented tree of files sorted by path
+func fileTree(files []TorrentFile) []string {
+	files = slices.Clone(files)
+	slices.SortFunc(files, func(a, b TorrentFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	var (
+		lines []string
+		dirs  []string
+	)
+	for _, file := range files {
+		parts := strings.Split(filepath.ToSlash(file.Path), "/")
+		fileDirs, name := parts[:len(parts)-1], parts[len(parts)-1]
+
+		// skip directories shared with the previous file
+		common := 0
+		for common < len(dirs) && common < len(fileDirs) && dirs[common] == fileDirs[common] {
+			common++
+		}
+		for depth := common; depth < len(fileDirs); depth++ {
+			lines = append(lines, strings.Repeat("  ", depth)+fileDirs[depth]+"/")
+		}
+		dirs = fileDirs
+
+		lines = append(lines, fmt.Sprintf("%s%s (%s)", strings.Repeat("  ", len(fileDirs)), name, formatSize(file.Size)))
+	}
+
+	return lines
+}
+
+// hashtags returns tags of the torrent and tags of the kinds of its files
+func hashtags(torrent Torrent) []string {
+	tags := slices.Clone(torrent.Tags)
+	for _, file := range torrent.Files {
+		if kind := mediaKind(filepath.Ext(file.Path)); kind != KindDocument {
+			tags = append(tags, string(kind))
+		}
+	}
+
+	var result []string
+	for _, tag := range tags {
+		tag = "#" + hashtag(tag)
+		if tag != "#" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+// hashtag replaces characters not allowed in hashtags with underscores
+func hashtag(tag string) string {
+	tag = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, strings.TrimPrefix(tag, "#"))
+
+	return strings.Trim(tag, "_")
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d Б", size)
+	}
+
+	units := []string{"КБ", "МБ", "ГБ", "ТБ"}
+	value := float64(size) / unit
+	i := 0
+	for ; value >= unit && i < len(units)-1; i++ {
+		value /= unit
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
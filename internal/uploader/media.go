@@ -0,0 +1,316 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aleksander-git/telegram-torrent/internal/mediainfo"
+	"github.com/gotd/td/telegram/message"
+	tduploader "github.com/gotd/td/telegram/uploader"
+	"github.com/gotd/td/tg"
+)
+
+// source is a content of a file to upload
+type source struct {
+	// name is a file name or path, its extension defines the kind of file
+	name   string
+	reader io.Reader
+	// size is a size of the content in bytes, -1 if it's unknown
+	size int64
+}
+
+// seeker returns reader of the source if it supports seeking, otherwise nil.
+// Metadata is read only from seekable sources, since the content is read twice
+func (s source) seeker() io.ReadSeeker {
+	rs, _ := s.reader.(io.ReadSeeker)
+	return rs
+}
+
+// openSource opens file located on the filePath. The returned closer closes the file
+func openSource(filePath string) (source, io.Closer, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return source{}, nil, fmt.Errorf("os.Open(%q): %w", filePath, err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return source{}, nil, fmt.Errorf("f.Stat(): %w", err)
+	}
+
+	return source{name: filePath, reader: f, size: stat.Size()}, f, nil
+}
+
+// fileInfo is a file to upload with its metadata
+type fileInfo struct {
+	path  string
+	kind  MediaKind
+	audio mediainfo.AudioTags
+	video mediainfo.VideoInfo
+	image mediainfo.ImageConfig
+}
+
+// Telegram limits for files sent as photos
+const (
+	photoMaxSize      = 10 << 20
+	photoMaxSidesSum  = 10000
+	photoMaxSideRatio = 20
+)
+
+// describePath opens the file located on the filePath and detects its kind and metadata.
+// A file which can't be opened is described by its extension only
+func (u *Uploader) describePath(filePath string) fileInfo {
+	src, closer, err := openSource(filePath)
+	if err != nil {
+		u.log.Debug("failed to open file", slog.String("path", filePath), slog.String("error", err.Error()))
+		return u.describe(source{name: filePath, size: -1})
+	}
+	defer closer.Close()
+
+	return u.describe(src)
+}
+
+// describe detects the kind of file and reads its metadata.
+// Metadata errors are not fatal: the file is sent without metadata.
+//
+// Position of the source reader is restored after reading
+func (u *Uploader) describe(src source) fileInfo {
+	file := fileInfo{
+		path: src.name,
+		kind: mediaKind(filepath.Ext(src.name)),
+	}
+
+	content := src.seeker()
+	if content == nil {
+		if file.kind == KindPhoto {
+			// image can't be checked against photo limits
+			file.kind = KindDocument
+		}
+		return file
+	}
+
+	switch file.kind {
+	case KindPhoto:
+		config, err := readImageConfig(content)
+		if err != nil {
+			u.log.Debug("failed to read image config",
+				slog.String("path", src.name),
+				slog.String("error", err.Error()),
+			)
+		}
+		file.image = config
+
+		if err != nil || !fitsPhoto(config, src.size) {
+			file.kind = KindDocument
+		}
+	case KindAudio:
+		tags, err := mediainfo.ReadAudioTags(content)
+		if err != nil {
+			u.log.Debug("failed to read audio tags",
+				slog.String("path", src.name),
+				slog.String("error", err.Error()),
+			)
+		}
+		file.audio = tags
+	case KindVideo:
+		info, err := mediainfo.ProbeVideo(content)
+		if err != nil {
+			u.log.Debug("failed to probe video",
+				slog.String("path", src.name),
+				slog.String("error", err.Error()),
+			)
+		} else if !info.Streamable {
+			u.log.Debug("video is sent without streaming support",
+				slog.String("path", src.name),
+			)
+		}
+		file.video = info
+	}
+
+	return file
+}
+
+// fitsPhoto reports whether the image can be sent as a photo,
+// otherwise Telegram will reject it or compress it too much
+func fitsPhoto(config mediainfo.ImageConfig, size int64) bool {
+	if config.Format == "gif" || config.Width == 0 || config.Height == 0 {
+		// animation would be lost
+		return false
+	}
+
+	longSide, shortSide := max(config.Width, config.Height), min(config.Width, config.Height)
+
+	return size >= 0 && size <= photoMaxSize &&
+		config.Width+config.Height <= photoMaxSidesSum &&
+		longSide <= shortSide*photoMaxSideRatio
+}
+
+func readImageConfig(content io.ReadSeeker) (config mediainfo.ImageConfig, err error) {
+	err = rewind(content, func(r io.Reader) (err error) {
+		config, err = mediainfo.ReadImageConfig(r)
+		return err
+	})
+
+	return config, err
+}
+
+// rewind calls fn and restores position of rs afterwards
+func rewind(rs io.ReadSeeker, fn func(r io.Reader) error) error {
+	pos, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("rs.Seek(0, io.SeekCurrent): %w", err)
+	}
+
+	fnErr := fn(rs)
+
+	if _, err := rs.Seek(pos, io.SeekStart); err != nil {
+		return fmt.Errorf("rs.Seek(%d, io.SeekStart): %w", pos, err)
+	}
+
+	return fnErr
+}
+
+// thumbnail uploads a thumbnail of the file generated from the image itself or its cover art.
+// nil is returned if there is no image to make a thumbnail of or it can't be uploaded
+func (u *Uploader) thumbnail(ctx context.Context, file fileInfo, src source) tg.InputFileClass {
+	var thumb []byte
+	err := func() (err error) {
+		switch {
+		case file.audio.Cover != nil:
+			thumb, err = mediainfo.Thumbnail(bytes.NewReader(file.audio.Cover))
+		case file.video.Cover != nil:
+			thumb, err = mediainfo.Thumbnail(bytes.NewReader(file.video.Cover))
+		case file.image.Format != "" && src.seeker() != nil &&
+			int64(file.image.Width)*int64(file.image.Height) <= mediainfo.ThumbnailMaxPixels:
+			// images too large to be sent as photos may be too large to decode
+			err = rewind(src.seeker(), func(r io.Reader) (err error) {
+				thumb, err = mediainfo.Thumbnail(r)
+				return err
+			})
+		}
+		return err
+	}()
+	if err != nil {
+		u.log.Debug("failed to make thumbnail", slog.String("path", file.path), slog.String("error", err.Error()))
+		return nil
+	}
+	if thumb == nil {
+		return nil
+	}
+
+	upload, err := u.uploader.Upload(ctx, tduploader.NewUpload("thumb.jpg", bytes.NewReader(thumb), int64(len(thumb))))
+	if err != nil {
+		u.log.Debug("failed to upload thumbnail", slog.String("path", file.path), slog.String("error", err.Error()))
+		return nil
+	}
+
+	return upload
+}
+
+// audioDocument marks document as audio and fills title, performer and duration from the tags
+func audioDocument(document *message.UploadedDocumentBuilder, tags mediainfo.AudioTags) *message.AudioDocumentBuilder {
+	audio := document.Audio().
+		Title(tags.Title).
+		Performer(tags.Performer)
+
+	if tags.Duration > 0 {
+		audio.Duration(tags.Duration)
+	}
+
+	return audio
+}
+
+// videoDocument marks document as video and fills its dimensions, duration and streaming support
+func videoDocument(document *message.UploadedDocumentBuilder, info mediainfo.VideoInfo) *message.VideoDocumentBuilder {
+	video := document.Video().
+		Resolution(info.Width, info.Height).
+		Duration(info.Duration)
+
+	if info.Streamable {
+		video.SupportsStreaming()
+	}
+
+	return video
+}
+
+// SortAlbum returns file paths ordered for sending.
+//
+// Files are grouped by directory. Inside a directory audio files with track numbers
+// go first ordered by disc and track, then other files ordered by name
+func (u *Uploader) SortAlbum(filePaths []string) []string {
+	files := sortAlbum(u.describeAll(filePaths))
+
+	sorted := make([]string, len(files))
+	for i, file := range files {
+		sorted[i] = file.path
+	}
+
+	return sorted
+}
+
+// describeAll describes the files in the order of filePaths
+func (u *Uploader) describeAll(filePaths []string) []fileInfo {
+	files := make([]fileInfo, len(filePaths))
+	for i, filePath := range filePaths {
+		files[i] = u.describePath(filePath)
+	}
+
+	return files
+}
+
+// sortAlbum orders the described files for sending, see SortAlbum
+func sortAlbum(files []fileInfo) []fileInfo {
+	type sortKey struct {
+		file    fileInfo
+		dir     string
+		tracked bool
+		disc    int
+		track   int
+	}
+
+	keys := make([]sortKey, len(files))
+	for i, file := range files {
+		key := sortKey{
+			file: file,
+			dir:  filepath.Dir(file.path),
+		}
+
+		if file.kind == KindAudio && file.audio.Track > 0 {
+			key.tracked = true
+			key.disc = file.audio.Disc
+			key.track = file.audio.Track
+		}
+
+		keys[i] = key
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case a.dir != b.dir:
+			return a.dir < b.dir
+		case a.tracked != b.tracked:
+			return a.tracked
+		case a.disc != b.disc:
+			return a.disc < b.disc
+		case a.track != b.track:
+			return a.track < b.track
+		default:
+			return a.file.path < b.file.path
+		}
+	})
+
+	sorted := make([]fileInfo, len(keys))
+	for i, key := range keys {
+		sorted[i] = key.file
+	}
+
+	return sorted
+}
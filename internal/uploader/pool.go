@@ -0,0 +1,92 @@
+package uploader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Balancer selects one of the uploaders for every upload.
+// It's implemented by gotdclient.Pool
+type Balancer interface {
+	// Acquire returns index of the selected uploader. release is called after the upload
+	Acquire(ctx context.Context) (index int, release func(), err error)
+}
+
+// Pool uploads files in parallel through several uploaders, e.g. ones of different bots.
+// Every upload is done by the uploader selected by the balancer
+type Pool struct {
+	uploaders []*Uploader
+	balancer  Balancer
+}
+
+// NewPool creates pool of the uploaders. Indexes returned by the balancer
+// must refer to uploaders, e.g. uploaders must be in order of gotdclient.Pool clients
+func NewPool(uploaders []*Uploader, balancer Balancer) (*Pool, error) {
+	if len(uploaders) == 0 {
+		return nil, errors.New("pool must contain at least one uploader")
+	}
+
+	return &Pool{
+		uploaders: uploaders,
+		balancer:  balancer,
+	}, nil
+}
+
+// Upload is Uploader.Upload of the selected uploader
+func (p *Pool) Upload(ctx context.Context, filePath string, targetDomain string) error {
+	return p.with(ctx, func(u *Uploader) error {
+		return u.Upload(ctx, filePath, targetDomain)
+	})
+}
+
+// UploadReader is Uploader.UploadReader of the selected uploader
+func (p *Pool) UploadReader(ctx context.Context, name string, r io.Reader, size int64, targetDomain string) error {
+	return p.with(ctx, func(u *Uploader) error {
+		return u.UploadReader(ctx, name, r, size, targetDomain)
+	})
+}
+
+// UploadAll is Uploader.UploadAll of the selected uploader
+func (p *Pool) UploadAll(ctx context.Context, filePaths []string, targetDomain string) error {
+	return p.with(ctx, func(u *Uploader) error {
+		return u.UploadAll(ctx, filePaths, targetDomain)
+	})
+}
+
+// UploadMulti is Uploader.UploadMulti of the selected uploader
+func (p *Pool) UploadMulti(ctx context.Context, filePath string, targetDomains []string) (results []TargetResult, err error) {
+	err = p.with(ctx, func(u *Uploader) error {
+		results, err = u.UploadMulti(ctx, filePath, targetDomains)
+		return err
+	})
+
+	return results, err
+}
+
+// UploadTorrent is Uploader.UploadTorrent of the selected uploader,
+// the whole torrent is posted by the same uploader
+func (p *Pool) UploadTorrent(ctx context.Context, torrent Torrent, targetDomain string) (post Post, err error) {
+	err = p.with(ctx, func(u *Uploader) error {
+		post, err = u.UploadTorrent(ctx, torrent, targetDomain)
+		return err
+	})
+
+	return post, err
+}
+
+// with calls fn with the uploader selected by the balancer
+func (p *Pool) with(ctx context.Context, fn func(u *Uploader) error) error {
+	index, release, err := p.balancer.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("p.balancer.Acquire(ctx): %w", err)
+	}
+	defer release()
+
+	if index < 0 || index >= len(p.uploaders) {
+		return fmt.Errorf("balancer selected uploader %d of %d", index, len(p.uploaders))
+	}
+
+	return fn(p.uploaders[index])
+}
@@ -0,0 +1,220 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// MediaKind is a kind of content the file is sent as
+type MediaKind string
+
+const (
+	// AnyKind matches files of every kind. It is used for templates
+	// that are not bound to a specific kind of content
+	AnyKind MediaKind = ""
+
+	KindDocument MediaKind = "document"
+	KindVideo    MediaKind = "video"
+	KindAudio    MediaKind = "audio"
+	KindPhoto    MediaKind = "photo"
+)
+
+// AnyTarget matches every target. It is used for templates
+// that are not bound to a specific channel or user
+const AnyTarget = ""
+
+// TemplateSettingPrefix is a prefix of the settings holding caption templates.
+//
+// Setting name format is caption_template[.<kind>][@<target>], e.g.:
+//
+//	caption_template                  - default template
+//	caption_template.video            - template for videos sent to any target
+//	caption_template@my_channel       - template for files of any kind sent to my_channel
+//	caption_template.audio@my_channel - template for audio files sent to my_channel
+const TemplateSettingPrefix = "caption_template"
+
+// TemplateSource provides global settings by their name prefix
+type TemplateSource interface {
+	GetGlobalSettings(ctx context.Context, prefix string) (map[string]string, error)
+}
+
+type templateKey struct {
+	kind   MediaKind
+	target string
+}
+
+// Templates is a registry of caption templates bound to media kinds and targets
+//
+// Templates loaded from the settings take precedence over the registered ones
+// at equal specificity, so admins can override captions without a redeploy.
+// A more specific registered template still wins over a less specific loaded one
+type Templates struct {
+	mu sync.RWMutex
+
+	registered map[templateKey]*template.Template
+	loaded     map[templateKey]*template.Template
+}
+
+func NewTemplates() *Templates {
+	return &Templates{
+		registered: make(map[templateKey]*template.Template),
+		loaded:     make(map[templateKey]*template.Template),
+	}
+}
+
+// Register adds a caption template for files of the kind sent to the target.
+// Use AnyKind and AnyTarget to make template match every kind or target
+func (t *Templates) Register(kind MediaKind, target string, messageTemplate string) error {
+	templ, err := compileTemplate(messageTemplate)
+	if err != nil {
+		return fmt.Errorf("compileTemplate(%q): %w", messageTemplate, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.registered[templateKey{kind: kind, target: normalizeTarget(target)}] = templ
+
+	return nil
+}
+
+// Load replaces templates loaded earlier with the ones stored in the settings.
+// Templates added by Register are kept
+func (t *Templates) Load(ctx context.Context, source TemplateSource) error {
+	settings, err := source.GetGlobalSettings(ctx, TemplateSettingPrefix)
+	if err != nil {
+		return fmt.Errorf("source.GetGlobalSettings(ctx, %q): %w", TemplateSettingPrefix, err)
+	}
+
+	loaded := make(map[templateKey]*template.Template, len(settings))
+	for name, value := range settings {
+		key, ok := parseTemplateSettingName(name)
+		if !ok {
+			continue
+		}
+
+		templ, err := compileTemplate(value)
+		if err != nil {
+			return fmt.Errorf("setting %q: compileTemplate(%q): %w", name, value, err)
+		}
+
+		loaded[key] = templ
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.loaded = loaded
+
+	return nil
+}
+
+// Reload calls Load if the changed setting named name holds a template.
+// Empty name means that any setting could change.
+//
+// It can be subscribed to settings.Listener to apply changes without a restart
+func (t *Templates) Reload(ctx context.Context, source TemplateSource, name string) error {
+	if name != "" && !strings.HasPrefix(name, TemplateSettingPrefix) {
+		return nil
+	}
+
+	return t.Load(ctx, source)
+}
+
+// Lookup returns the most specific template for files of the kind sent to the target.
+//
+// Templates are looked up in the following order:
+// kind and target, kind and any target, any kind and target, any kind and any target.
+// At every step a loaded template is preferred to a registered one.
+// nil is returned if there is no matching template
+func (t *Templates) Lookup(kind MediaKind, target string) *template.Template {
+	target = normalizeTarget(target)
+	candidates := []templateKey{
+		{kind: kind, target: target},
+		{kind: kind, target: AnyTarget},
+		{kind: AnyKind, target: target},
+		{kind: AnyKind, target: AnyTarget},
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, key := range candidates {
+		if templ, ok := t.loaded[key]; ok {
+			return templ
+		}
+		if templ, ok := t.registered[key]; ok {
+			return templ
+		}
+	}
+
+	return nil
+}
+
+// merge adds templates registered in other which are missing in t
+func (t *Templates) merge(other *Templates) {
+	if other == nil || other == t {
+		return
+	}
+
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, templ := range other.registered {
+		if _, ok := t.registered[key]; !ok {
+			t.registered[key] = templ
+		}
+	}
+}
+
+func parseTemplateSettingName(name string) (key templateKey, ok bool) {
+	rest, found := strings.CutPrefix(name, TemplateSettingPrefix)
+	if !found {
+		return templateKey{}, false
+	}
+
+	rest, key.target, _ = strings.Cut(rest, "@")
+	key.target = normalizeTarget(key.target)
+
+	switch {
+	case rest == "":
+		key.kind = AnyKind
+	case strings.HasPrefix(rest, "."):
+		key.kind = MediaKind(rest[1:])
+	default:
+		// other setting sharing the prefix, e.g. caption_templates
+		return templateKey{}, false
+	}
+
+	return key, true
+}
+
+func normalizeTarget(target string) string {
+	return strings.ToLower(strings.TrimPrefix(target, "@"))
+}
+
+func compileTemplate(messageTemplate string) (*template.Template, error) {
+	templ, err := template.New("message").Parse(messageTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("template.New(\"message\").Parse(%q): %w", messageTemplate, err)
+	}
+
+	return templ, nil
+}
+
+func executeTemplate(templ *template.Template, data any) (string, error) {
+	var buf bytes.Buffer
+
+	if err := templ.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("template.Execute(): %w", err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,90 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTemplateSettingName(t *testing.T) {
+	tests := []struct {
+		name string
+		key  templateKey
+		ok   bool
+	}{
+		{name: "caption_template", key: templateKey{kind: AnyKind, target: AnyTarget}, ok: true},
+		{name: "caption_template.video", key: templateKey{kind: KindVideo, target: AnyTarget}, ok: true},
+		{name: "caption_template@My_Channel", key: templateKey{kind: AnyKind, target: "my_channel"}, ok: true},
+		{name: "caption_template.audio@my_channel", key: templateKey{kind: KindAudio, target: "my_channel"}, ok: true},
+		{name: "caption_templates", ok: false},
+		{name: "channel_link", ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, ok := parseTemplateSettingName(test.name)
+			require.Equal(t, test.ok, ok)
+			require.Equal(t, test.key, key)
+		})
+	}
+}
+
+type templateSource map[string]string
+
+func (s templateSource) GetGlobalSettings(_ context.Context, _ string) (map[string]string, error) {
+	return s, nil
+}
+
+func TestTemplates_Lookup(t *testing.T) {
+	templates := NewTemplates()
+	require.NoError(t, templates.Register(AnyKind, AnyTarget, "default"))
+	require.NoError(t, templates.Register(AnyKind, "@news", "news"))
+	require.NoError(t, templates.Register(KindVideo, AnyTarget, "video"))
+	require.NoError(t, templates.Register(KindAudio, AnyTarget, "registered audio"))
+	require.NoError(t, templates.Register(KindAudio, "@podcasts", "podcasts"))
+	require.NoError(t, templates.Load(context.Background(), templateSource{
+		"caption_template.video@News": "video news",
+		"caption_template.audio":      "audio",
+	}))
+
+	tests := []struct {
+		name    string
+		kind    MediaKind
+		target  string
+		message string
+	}{
+		{name: "kind and target", kind: KindVideo, target: "news", message: "video news"},
+		{name: "kind", kind: KindVideo, target: "music", message: "video"},
+		// loaded template overrides the registered one of the same specificity
+		{name: "kind loaded", kind: KindAudio, target: "news", message: "audio"},
+		// registered template is more specific than the loaded one
+		{name: "kind and target registered", kind: KindAudio, target: "podcasts", message: "podcasts"},
+		{name: "target", kind: KindDocument, target: "@NEWS", message: "news"},
+		{name: "default", kind: KindPhoto, target: "music", message: "default"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			templ := templates.Lookup(test.kind, test.target)
+			require.NotNil(t, templ)
+
+			var buf bytes.Buffer
+			require.NoError(t, templ.Execute(&buf, nil))
+			require.Equal(t, test.message, buf.String())
+		})
+	}
+
+	require.Nil(t, NewTemplates().Lookup(KindVideo, "news"))
+}
+
+func TestUploader_WithTemplates(t *testing.T) {
+	templates := NewTemplates()
+	require.NoError(t, templates.Register(KindVideo, AnyTarget, "video"))
+
+	u := New(nil, nil, nil).WithMessage("default").WithTemplates(templates)
+
+	require.NotNil(t, u.templates.Lookup(KindVideo, "news"))
+	require.NotNil(t, u.templates.Lookup(KindDocument, "news"))
+}
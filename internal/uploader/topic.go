@@ -0,0 +1,116 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	tdhtml "github.com/gotd/td/telegram/message/html"
+)
+
+// TopicCreator creates forum topics in supergroups.
+// It's implemented by gotdclient.Resolver
+type TopicCreator interface {
+	// CreateTopic creates topic titled title in the forum supergroup targetDomain and returns its ID.
+	// 0 is returned without an error if the target isn't a forum supergroup
+	CreateTopic(ctx context.Context, targetDomain string, title string) (int, error)
+}
+
+// PostStore stores IDs of the torrent post by the magnet link of the torrent.
+// It's implemented by backend.Database
+type PostStore interface {
+	UpdateTorrentTopicID(ctx context.Context, torrentLink string, topicID int64) error
+	UpdateTorrentMessageID(ctx context.Context, torrentLink string, messageID int64) error
+}
+
+// WithPostStore makes UploadTorrent store IDs of the forum topic and the header post
+// of the torrents having Torrent.Link
+func (u *Uploader) WithPostStore(posts PostStore) *Uploader {
+	u.posts = posts
+
+	return u
+}
+
+// WithTopics enables posting of every torrent into its own topic
+// when the target is a forum supergroup, see UploadTorrent
+func (u *Uploader) WithTopics(topics TopicCreator) *Uploader {
+	u.topics = topics
+
+	return u
+}
+
+// UploadTorrent posts the torrent to targetDomain: first a header post with its name, size,
+// file tree, hashtags and infohash and then every file as a reply to the header, see UploadAll.
+//
+// If topics are enabled by WithTopics and the target is a forum supergroup,
+// a new topic named after the torrent is created and the whole post is placed inside it.
+// IDs of the sent messages are returned even if some files failed to upload
+func (u *Uploader) UploadTorrent(ctx context.Context, torrent Torrent, targetDomain string) (Post, error) {
+	const src = "Uploader.UploadTorrent"
+	log := u.log.With(
+		slog.String("src", src),
+	)
+
+	var post Post
+
+	if u.topics != nil {
+		topicID, err := u.topics.CreateTopic(ctx, targetDomain, torrent.Name)
+		if err != nil {
+			return post, fmt.Errorf("u.topics.CreateTopic(ctx, %q, %q): %w", targetDomain, torrent.Name, err)
+		}
+		post.TopicID = topicID
+
+		if u.posts != nil && torrent.Link != "" && topicID != 0 {
+			if err := u.posts.UpdateTorrentTopicID(ctx, torrent.Link, int64(topicID)); err != nil {
+				// the topic is already created, so the torrent is posted anyway
+				log.Warn("unable to store topic ID",
+					slog.String("name", torrent.Name),
+					slog.Int("topic_id", topicID),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}
+
+	log.Debug("uploading torrent",
+		slog.String("name", torrent.Name),
+		slog.String("target", targetDomain),
+		slog.Int("topic_id", post.TopicID),
+	)
+
+	updates, err := u.to(targetDomain, post.TopicID).StyledText(ctx, tdhtml.String(nil, headerText(torrent)))
+	if err != nil {
+		return post, fmt.Errorf("failed to send header of torrent %q to target %q: %w", torrent.Name, targetDomain, err)
+	}
+
+	post.HeaderID = u.sentMessageID(updates, torrent.Name, targetDomain)
+
+	if u.posts != nil && torrent.Link != "" && post.HeaderID != 0 {
+		if err := u.posts.UpdateTorrentMessageID(ctx, torrent.Link, int64(post.HeaderID)); err != nil {
+			log.Warn("unable to store header ID",
+				slog.String("name", torrent.Name),
+				slog.Int("header_id", post.HeaderID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	filePaths := make([]string, 0, len(torrent.Files))
+	for _, file := range torrent.Files {
+		filePaths = append(filePaths, filepath.Join(torrent.Dir, file.Path))
+	}
+
+	// files are still posted inside the topic if ID of the header is unknown
+	replyTo := post.HeaderID
+	if replyTo == 0 {
+		replyTo = post.TopicID
+	}
+
+	post.FileIDs, err = u.uploadAll(ctx, filePaths, targetDomain, replyTo)
+	if err != nil {
+		return post, fmt.Errorf("u.uploadAll(ctx, %d files, %q, %d): %w", len(filePaths), targetDomain, replyTo, err)
+	}
+
+	return post, nil
+}
@@ -0,0 +1,64 @@
+package uploader_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/tg"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aleksander-git/telegram-torrent/internal/uploader"
+)
+
+type topicCreator int
+
+func (c topicCreator) CreateTopic(_ context.Context, _ string, _ string) (int, error) {
+	return int(c), nil
+}
+
+type postStore struct {
+	topicIDs   map[string]int64
+	messageIDs map[string]int64
+}
+
+func (s postStore) UpdateTorrentTopicID(_ context.Context, torrentLink string, topicID int64) error {
+	s.topicIDs[torrentLink] = topicID
+	return nil
+}
+
+func (s postStore) UpdateTorrentMessageID(_ context.Context, torrentLink string, messageID int64) error {
+	s.messageIDs[torrentLink] = messageID
+	return nil
+}
+
+func TestUploader_UploadTorrent(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello"), 0o644))
+
+	sender := message.NewSender(tg.NewClient(invoker{}))
+	resolver := channels{sender: sender, ids: map[string]int64{"forum": 5}}
+	posts := postStore{topicIDs: map[string]int64{}, messageIDs: map[string]int64{}}
+
+	u := uploader.New(slog.Default(), fileUploader{}, resolver).
+		WithTopics(topicCreator(7)).
+		WithPostStore(posts)
+
+	torrent := uploader.Torrent{
+		Name:  "hello",
+		Link:  "magnet:?xt=urn:btih:hello",
+		Dir:   dir,
+		Files: []uploader.TorrentFile{{Path: "hello.txt", Size: 5}},
+	}
+
+	// the header is sent, but its ID is missing in the updates
+	post, err := u.UploadTorrent(context.Background(), torrent, "forum")
+	require.NoError(t, err)
+	require.Equal(t, uploader.Post{TopicID: 7, FileIDs: []int{50}}, post)
+
+	require.Equal(t, map[string]int64{torrent.Link: 7}, posts.topicIDs)
+	require.Empty(t, posts.messageIDs)
+}
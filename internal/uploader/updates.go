@@ -0,0 +1,69 @@
+package uploader
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gotd/td/tg"
+)
+
+// sentMessageID returns ID of the message with the file named name sent to targetDomain.
+// 0 is returned and a warning is logged if the updates have no sent message:
+// the file is already sent, so failing would make callers send it again
+func (u *Uploader) sentMessageID(updates tg.UpdatesClass, name string, targetDomain string) int {
+	const src = "Uploader.sentMessageID"
+	log := u.log.With(
+		slog.String("src", src),
+	)
+
+	id, err := sentMessageID(updates)
+	if err != nil {
+		log.Warn("unable to get ID of the sent message",
+			slog.String("name", name),
+			slog.String("target", targetDomain),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return id
+}
+
+// sentMessageID returns ID of the message sent by the request that returned updates
+func sentMessageID(updates tg.UpdatesClass) (int, error) {
+	ids := sentMessageIDs(updates)
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("no sent message in updates %T", updates)
+	}
+
+	return ids[0], nil
+}
+
+// sentMessageIDs returns IDs of the messages sent by the request that returned updates,
+// e.g. of every message of an album
+func sentMessageIDs(updates tg.UpdatesClass) []int {
+	var list []tg.UpdateClass
+	switch updates := updates.(type) {
+	case *tg.UpdateShortSentMessage:
+		return []int{updates.ID}
+	case *tg.Updates:
+		list = updates.Updates
+	case *tg.UpdatesCombined:
+		list = updates.Updates
+	default:
+		return nil
+	}
+
+	var ids []int
+	for _, update := range list {
+		switch update := update.(type) {
+		case *tg.UpdateNewMessage:
+			ids = append(ids, update.Message.GetID())
+		case *tg.UpdateNewChannelMessage:
+			ids = append(ids, update.Message.GetID())
+		case *tg.UpdateNewScheduledMessage:
+			ids = append(ids, update.Message.GetID())
+		}
+	}
+
+	return ids
+}
@@ -1,23 +1,28 @@
 package uploader
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"github.com/gotd/td/telegram/message/peer"
+	"io"
 	"log/slog"
 	"mime"
 	"path/filepath"
 	"strings"
-	"text/template"
 
+	"github.com/aleksander-git/telegram-torrent/internal/mediainfo"
 	"github.com/gotd/td/telegram/message"
 	"github.com/gotd/td/telegram/message/html"
+	tduploader "github.com/gotd/td/telegram/uploader"
 	"github.com/gotd/td/tg"
 )
 
+// FileUploader uploads files to the Telegram server.
+// It's implemented by github.com/gotd/td/telegram/uploader.Uploader
 type FileUploader interface {
-	FromPath(ctx context.Context, path string) (tg.InputFileClass, error)
+	// Upload uploads file content read from the upload.
+	// Content of unknown size (-1) can't exceed the limit for small files
+	Upload(ctx context.Context, upload *tduploader.Upload) (tg.InputFileClass, error)
 }
 
 type Resolver interface {
@@ -30,7 +35,12 @@ type Uploader struct {
 	uploader FileUploader
 	resolver Resolver
 
-	messageTemplate string
+	templates *Templates
+	topics    TopicCreator
+	posts     PostStore
+	// templateErr is an error of the template passed to WithMessage,
+	// it's returned by Upload
+	templateErr error
 }
 
 func New(
@@ -39,10 +49,10 @@ func New(
 	resolver Resolver,
 ) *Uploader {
 	return &Uploader{
-		log:             log,
-		uploader:        uploader,
-		resolver:        resolver,
-		messageTemplate: "",
+		log:       log,
+		uploader:  uploader,
+		resolver:  resolver,
+		templates: NewTemplates(),
 	}
 }
 
@@ -50,9 +60,21 @@ func New(
 //
 // You can use template variables and functions or html tags like <i> or <b> to pretty message
 //
-// Available template variables: {{.FileName}}, {{.Extension}}, {{.IsVideo}}, {{.IsAudio}}
+// Available template variables: {{.FileName}}, {{.Extension}}, {{.Kind}}, {{.Target}}, {{.IsVideo}}, {{.IsAudio}}
+// and audio tags: {{.Audio.Title}}, {{.Audio.Performer}}, {{.Audio.Album}}, {{.Audio.Track}}, {{.Audio.Duration}}
+// and video info: {{.Video.Width}}, {{.Video.Height}}, {{.Video.Duration}}
 func (u *Uploader) WithMessage(messageTemplate string) *Uploader {
-	u.messageTemplate = messageTemplate
+	u.templateErr = u.templates.Register(AnyKind, AnyTarget, messageTemplate)
+
+	return u
+}
+
+// WithTemplates makes the uploader use the given registry, e.g. one loaded from the settings by Templates.Load.
+// Templates registered earlier, e.g. by WithMessage, are added to the registry
+// unless it has its own ones for the same kind and target
+func (u *Uploader) WithTemplates(templates *Templates) *Uploader {
+	templates.merge(u.templates)
+	u.templates = templates
 
 	return u
 }
@@ -67,71 +89,298 @@ func (u *Uploader) Upload(ctx context.Context, filePath string, targetDomain str
 
 	log.Debug("uploading file", slog.String("path", filePath))
 
-	upload, err := u.uploader.FromPath(ctx, filePath)
+	_, err = u.sendPath(ctx, u.describePath(filePath), targetDomain, 0)
+	return err
+}
+
+// UploadReader uploads content of the file named name read from r to the Telegram server
+// and sends it to targetDomain (channel name or username).
+//
+// It allows to stream content without writing it to a disk, e.g. from torrent storage reader.
+// size is a size of the content in bytes or -1 if it's unknown, but content of unknown size
+// can't exceed the Telegram limit for small files (10 MB).
+// Metadata (tags, dimensions, thumbnails) is read only if r implements io.ReadSeeker
+func (u *Uploader) UploadReader(ctx context.Context, name string, r io.Reader, size int64, targetDomain string) error {
+	const src = "Uploader.UploadReader"
+	log := u.log.With(
+		slog.String("src", src),
+	)
+
+	log.Debug("uploading file", slog.String("name", name), slog.Int64("size", size))
+
+	_, err := u.send(ctx, source{name: name, reader: r, size: size}, targetDomain, 0)
+	return err
+}
+
+// send uploads the source and sends it to targetDomain as a reply to the message replyTo, if it's not 0.
+// ID of the sent message is returned
+func (u *Uploader) send(ctx context.Context, src source, targetDomain string, replyTo int) (int, error) {
+	return u.sendFile(ctx, src, u.describe(src), targetDomain, replyTo)
+}
+
+// sendFile uploads the source described beforehand, so its metadata isn't read again,
+// and sends it to targetDomain. ID of the sent message is returned
+func (u *Uploader) sendFile(ctx context.Context, src source, file fileInfo, targetDomain string, replyTo int) (int, error) {
+	media, err := u.prepare(ctx, src, file, targetDomain)
+	if err != nil {
+		return 0, fmt.Errorf("u.prepare(ctx, %q, %q): %w", src.name, targetDomain, err)
+	}
+
+	updates, err := u.to(targetDomain, replyTo).Media(ctx, media)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send file %q to target %q: %w", src.name, targetDomain, err)
+	}
+
+	return u.sentMessageID(updates, src.name, targetDomain), nil
+}
+
+// sendPath opens the described file and sends it to targetDomain, see send
+func (u *Uploader) sendPath(ctx context.Context, file fileInfo, targetDomain string, replyTo int) (int, error) {
+	content, closer, err := openSource(file.path)
 	if err != nil {
-		return fmt.Errorf("u.uploader.FromPath(ctx, %q): %w", filePath, err)
+		return 0, fmt.Errorf("openSource(%q): %w", file.path, err)
+	}
+	defer closer.Close()
+
+	return u.sendFile(ctx, content, file, targetDomain, replyTo)
+}
+
+// to returns message builder sending to targetDomain as a reply to the message replyTo, if it's not 0.
+// Replies to the first message of a forum topic are posted inside the topic
+func (u *Uploader) to(targetDomain string, replyTo int) *message.Builder {
+	builder := &u.resolver.Resolve(targetDomain).Builder
+	if replyTo != 0 {
+		builder = builder.Reply(replyTo)
 	}
 
-	var msg string
-	if u.messageTemplate != "" {
-		msg, err = parseMessageTemplate(u.messageTemplate, filePath)
+	return builder
+}
+
+// maxAlbumSize is the maximum number of files Telegram allows in a single album
+const maxAlbumSize = 10
+
+// UploadAlbum uploads files located on the filePaths to the Telegram server
+// and sends them to targetDomain as a single album of 2 to 10 files
+func (u *Uploader) UploadAlbum(ctx context.Context, filePaths []string, targetDomain string) error {
+	_, err := u.uploadAlbum(ctx, u.describeAll(filePaths), targetDomain, 0)
+	return err
+}
+
+// uploadAlbum sends the album as a reply to the message replyTo, if it's not 0.
+// IDs of the sent messages are returned
+func (u *Uploader) uploadAlbum(ctx context.Context, files []fileInfo, targetDomain string, replyTo int) ([]int, error) {
+	const src = "Uploader.uploadAlbum"
+	log := u.log.With(
+		slog.String("src", src),
+	)
+
+	if len(files) < 2 || len(files) > maxAlbumSize {
+		return nil, fmt.Errorf("album must contain from 2 to %d files, got %d", maxAlbumSize, len(files))
+	}
+
+	log.Debug("uploading album", slog.Int("files", len(files)))
+
+	album := make([]message.MultiMediaOption, 0, len(files))
+	for _, file := range files {
+		media, err := u.preparePath(ctx, file, targetDomain)
 		if err != nil {
-			return fmt.Errorf("parseMessageTemplate(%q, %q): %w", u.messageTemplate, filePath, err)
+			return nil, fmt.Errorf("u.preparePath(ctx, %q, %q): %w", file.path, targetDomain, err)
 		}
+		album = append(album, media)
 	}
 
-	var extension = filepath.Ext(filePath)
-	document := message.UploadedDocument(upload, html.String(nil, msg)).
-		MIME(mime.TypeByExtension(extension)).
-		Filename(filepath.Base(filePath))
+	updates, err := u.to(targetDomain, replyTo).Album(ctx, album[0], album[1:]...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send album of %d files to target %q: %w", len(files), targetDomain, err)
+	}
 
-	switch {
-	case isAudio(extension):
-		document.Audio()
-	case isVideo(extension):
-		document.Video()
+	return sentMessageIDs(updates), nil
+}
+
+// UploadAll uploads files one by one to targetDomain.
+// Audio files are ordered by disc and track numbers, see SortAlbum.
+// Consecutive photos are sent as albums
+func (u *Uploader) UploadAll(ctx context.Context, filePaths []string, targetDomain string) error {
+	_, err := u.uploadAll(ctx, filePaths, targetDomain, 0)
+	return err
+}
+
+// uploadAll sends every file as a reply to the message replyTo, if it's not 0.
+// IDs of the sent messages are returned
+func (u *Uploader) uploadAll(ctx context.Context, filePaths []string, targetDomain string, replyTo int) ([]int, error) {
+	var (
+		ids    []int
+		photos []fileInfo
+	)
+
+	flushPhotos := func() error {
+		defer func() {
+			photos = photos[:0]
+		}()
+
+		switch len(photos) {
+		case 0:
+			return nil
+		case 1:
+			id, err := u.sendPath(ctx, photos[0], targetDomain, replyTo)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		default:
+			albumIDs, err := u.uploadAlbum(ctx, photos, targetDomain, replyTo)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, albumIDs...)
+		}
+
+		return nil
 	}
 
-	target := u.resolver.Resolve(targetDomain)
+	// every file is described once, the metadata is reused for sending
+	for _, file := range sortAlbum(u.describeAll(filePaths)) {
+		if file.kind == KindPhoto {
+			photos = append(photos, file)
+			if len(photos) == maxAlbumSize {
+				if err := flushPhotos(); err != nil {
+					return ids, fmt.Errorf("failed to upload photos to %q: %w", targetDomain, err)
+				}
+			}
+			continue
+		}
+
+		if err := flushPhotos(); err != nil {
+			return ids, fmt.Errorf("failed to upload photos to %q: %w", targetDomain, err)
+		}
 
-	if _, err := target.Media(ctx, document); err != nil {
-		return fmt.Errorf("failed to send file %q to target %q: %w", filePath, targetDomain, err)
+		id, err := u.sendPath(ctx, file, targetDomain, replyTo)
+		if err != nil {
+			return ids, fmt.Errorf("u.sendPath(ctx, %q, %q): %w", file.path, targetDomain, err)
+		}
+		ids = append(ids, id)
 	}
 
-	return nil
+	if err := flushPhotos(); err != nil {
+		return ids, fmt.Errorf("failed to upload photos to %q: %w", targetDomain, err)
+	}
+
+	return ids, nil
 }
 
-func parseMessageTemplate(messageTemplate, filePath string) (str string, err error) {
-	templ, err := template.New("message").Parse(messageTemplate)
+// preparePath opens the described file and uploads it for an album, see prepare
+func (u *Uploader) preparePath(ctx context.Context, file fileInfo, targetDomain string) (message.MultiMediaOption, error) {
+	src, closer, err := openSource(file.path)
 	if err != nil {
-		return "", fmt.Errorf("template.New(\"message\").Parse(%q): %w", messageTemplate, err)
+		return nil, fmt.Errorf("openSource(%q): %w", file.path, err)
 	}
+	defer closer.Close()
+
+	return u.prepare(ctx, src, file, targetDomain)
+}
 
-	var buf bytes.Buffer
+// prepare uploads the described source with its thumbnail and builds media to send to targetDomain
+func (u *Uploader) prepare(ctx context.Context, src source, file fileInfo, targetDomain string) (message.MultiMediaOption, error) {
+	if u.templateErr != nil {
+		return nil, fmt.Errorf("invalid message template: %w", u.templateErr)
+	}
 
-	err = templ.Execute(&buf, struct {
+	msg, err := u.caption(file, targetDomain)
+	if err != nil {
+		return nil, fmt.Errorf("u.caption(%q, %q): %w", src.name, targetDomain, err)
+	}
+
+	return u.upload(ctx, src, file, msg)
+}
+
+// upload uploads the described source with its thumbnail and builds media captioned with msg
+func (u *Uploader) upload(ctx context.Context, src source, file fileInfo, msg string) (message.MultiMediaOption, error) {
+	var thumb tg.InputFileClass
+	if file.kind != KindPhoto {
+		// thumbnail is made before the upload consumes the content
+		thumb = u.thumbnail(ctx, file, src)
+	}
+
+	name := filepath.Base(src.name)
+	upload, err := u.uploader.Upload(ctx, tduploader.NewUpload(name, src.reader, src.size))
+	if err != nil {
+		return nil, fmt.Errorf("u.uploader.Upload(ctx, %q): %w", src.name, err)
+	}
+
+	if file.kind == KindPhoto {
+		return message.UploadedPhoto(upload, html.String(nil, msg)), nil
+	}
+
+	document := message.UploadedDocument(upload, html.String(nil, msg)).
+		MIME(mime.TypeByExtension(filepath.Ext(name))).
+		Filename(name)
+
+	if thumb != nil {
+		document.Thumb(thumb)
+	}
+
+	switch file.kind {
+	case KindAudio:
+		return audioDocument(document, file.audio), nil
+	case KindVideo:
+		return videoDocument(document, file.video), nil
+	default:
+		return document, nil
+	}
+}
+
+// caption renders the template registered for the kind of file and the target.
+// Empty caption is returned if there is no such template
+func (u *Uploader) caption(file fileInfo, targetDomain string) (string, error) {
+	templ := u.templates.Lookup(file.kind, targetDomain)
+	if templ == nil {
+		return "", nil
+	}
+
+	extension := filepath.Ext(file.path)
+
+	return executeTemplate(templ, struct {
 		FileName  string
 		Extension string
+		Kind      MediaKind
+		Target    string
 		IsVideo   bool
 		IsAudio   bool
+		Audio     mediainfo.AudioTags
+		Video     mediainfo.VideoInfo
 	}{
-		FileName:  filepath.Base(filePath),
-		Extension: filepath.Ext(filePath),
-		IsVideo:   isVideo(filepath.Ext(filePath)),
-		IsAudio:   isAudio(filepath.Ext(filePath)),
+		FileName:  filepath.Base(file.path),
+		Extension: extension,
+		Kind:      file.kind,
+		Target:    targetDomain,
+		IsVideo:   isVideo(extension),
+		IsAudio:   isAudio(extension),
+		Audio:     file.audio,
+		Video:     file.video,
 	})
+}
 
-	if err != nil {
-		return "", fmt.Errorf("template.Execute(): %w", err)
+func mediaKind(ext string) MediaKind {
+	switch {
+	case isImage(ext):
+		return KindPhoto
+	case isAudio(ext):
+		return KindAudio
+	case isVideo(ext):
+		return KindVideo
+	default:
+		return KindDocument
 	}
-
-	return buf.String(), nil
 }
 
 func isAudio(ext string) bool {
 	return commonMimeType(ext) == "audio"
 }
 
+func isImage(ext string) bool {
+	return commonMimeType(ext) == "image"
+}
+
 func isVideo(ext string) bool {
 	return commonMimeType(ext) == "video"
 }